@@ -795,13 +795,19 @@ func (s *Service) sqlAdviceForMybatisMapperFile(ctx context.Context, datum *myba
 	if err != nil {
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to extract environment ids").SetInternal(err)
 	}
+	// The configuration without the environments, likes the one only declaring the mappers and the type aliases,
+	// has no environment to check.
+	var confEnvironments []configparser.Environment
+	if conf != nil {
+		confEnvironments = conf.Environments
+	}
 
 	allEnvironments, err := s.store.ListEnvironmentV2(ctx, &store.FindEnvironmentMessage{})
 	if err != nil {
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to list environments").SetInternal(err)
 	}
 
-	for _, confEnv := range conf.Environments {
+	for _, confEnv := range confEnvironments {
 		environmentIDs = append(environmentIDs, confEnv.ID)
 		for _, env := range allEnvironments {
 			if strings.EqualFold(env.Title, confEnv.ID) {
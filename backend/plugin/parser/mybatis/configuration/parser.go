@@ -12,6 +12,8 @@ import (
 // Configuration is the root element of mybatis configuration xml file.
 type Configuration struct {
 	Environments []Environment
+	Mappers      []Mapper
+	TypeAliases  []TypeAlias
 }
 
 // Environment is the element of environments in mybatis configuration xml file.
@@ -20,6 +22,23 @@ type Environment struct {
 	JDBCConnString string
 }
 
+// Mapper is the element of mappers in mybatis configuration xml file, which locates the mapper by one of the
+// classpath resource, the url, the mapper interface class or the package of the mapper interfaces.
+type Mapper struct {
+	Resource string
+	URL      string
+	Class    string
+	Package  string
+}
+
+// TypeAlias is the element of typeAliases in mybatis configuration xml file, which is either the alias of a type,
+// or the package whose classes are aliased by their simple names.
+type TypeAlias struct {
+	Alias   string
+	Type    string
+	Package string
+}
+
 // ParseConfiguration parses the mybatis configuration xml file likes below:
 //
 // <configuration>
@@ -32,8 +51,19 @@ type Environment struct {
 //	     ...
 //	   </environment>
 //	</environments>
+//	<typeAliases>
+//	  <typeAlias alias="Blog" type="org.mybatis.example.Blog"/>
+//	  <package name="org.mybatis.domain"/>
+//	</typeAliases>
+//	<mappers>
+//	  <mapper resource="org/mybatis/example/BlogMapper.xml"/>
+//	  <package name="org.mybatis.builder"/>
+//	</mappers>
 //
 // </configuration>.
+//
+// It returns nil if the configuration declares none of the environments, the type aliases and the mappers, and
+// the environments are empty if the configuration only declares the type aliases or the mappers.
 func ParseConfiguration(configurationXML string) (*Configuration, error) {
 	type Environments struct {
 		Environment []struct {
@@ -45,24 +75,47 @@ func ParseConfiguration(configurationXML string) (*Configuration, error) {
 		} `xml:"environment"`
 	}
 
+	type Mappers struct {
+		Elements []struct {
+			XMLName  xml.Name
+			Resource string `xml:"resource,attr"`
+			URL      string `xml:"url,attr"`
+			Class    string `xml:"class,attr"`
+			Name     string `xml:"name,attr"`
+		} `xml:",any"`
+	}
+
+	type TypeAliases struct {
+		Elements []struct {
+			XMLName xml.Name
+			Alias   string `xml:"alias,attr"`
+			Type    string `xml:"type,attr"`
+			Name    string `xml:"name,attr"`
+		} `xml:",any"`
+	}
+
 	reader := strings.NewReader(configurationXML)
 	d := xml.NewDecoder(reader)
+	var conf *Configuration
 	for {
 		token, err := d.Token()
 		if err != nil {
 			if err == io.EOF {
-				return nil, nil
+				return conf, nil
 			}
 			return nil, errors.Wrapf(err, "failed to read token")
 		}
 		switch t := token.(type) {
 		case xml.StartElement:
-			if t.Name.Local == "environments" {
+			switch t.Name.Local {
+			case "environments":
 				var environments Environments
 				if err := d.DecodeElement(&environments, &t); err != nil {
 					return nil, errors.Wrapf(err, "failed to decode environments")
 				}
-				var conf Configuration
+				if conf == nil {
+					conf = &Configuration{}
+				}
 				for _, environment := range environments.Environment {
 					for _, property := range environment.Properties {
 						if property.Name == "url" {
@@ -73,7 +126,49 @@ func ParseConfiguration(configurationXML string) (*Configuration, error) {
 						}
 					}
 				}
-				return &conf, nil
+			case "typeAliases":
+				var typeAliases TypeAliases
+				if err := d.DecodeElement(&typeAliases, &t); err != nil {
+					return nil, errors.Wrapf(err, "failed to decode type aliases")
+				}
+				if conf == nil {
+					conf = &Configuration{}
+				}
+				for _, element := range typeAliases.Elements {
+					switch element.XMLName.Local {
+					case "typeAlias":
+						conf.TypeAliases = append(conf.TypeAliases, TypeAlias{
+							Alias: element.Alias,
+							Type:  element.Type,
+						})
+					case "package":
+						conf.TypeAliases = append(conf.TypeAliases, TypeAlias{
+							Package: element.Name,
+						})
+					}
+				}
+			case "mappers":
+				var mappers Mappers
+				if err := d.DecodeElement(&mappers, &t); err != nil {
+					return nil, errors.Wrapf(err, "failed to decode mappers")
+				}
+				if conf == nil {
+					conf = &Configuration{}
+				}
+				for _, element := range mappers.Elements {
+					switch element.XMLName.Local {
+					case "mapper":
+						conf.Mappers = append(conf.Mappers, Mapper{
+							Resource: element.Resource,
+							URL:      element.URL,
+							Class:    element.Class,
+						})
+					case "package":
+						conf.Mappers = append(conf.Mappers, Mapper{
+							Package: element.Name,
+						})
+					}
+				}
 			}
 		default:
 		}
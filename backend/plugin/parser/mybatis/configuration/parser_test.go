@@ -38,8 +38,14 @@ func TestExtractEnvironmentIDs(t *testing.T) {
 		</dataSource>
 	</environment>
 	</environments>
+	<typeAliases>
+	<typeAlias alias="Blog" type="org.mybatis.example.Blog"/>
+	<package name="org.mybatis.domain"/>
+	</typeAliases>
 	<mappers>
 	<mapper resource="org/mybatis/example/BlogMapper.xml"/>
+	<mapper class="org.mybatis.example.PostMapper"/>
+	<package name="org.mybatis.builder"/>
 	</mappers>
 </configuration>
 `,
@@ -54,8 +60,57 @@ func TestExtractEnvironmentIDs(t *testing.T) {
 						JDBCConnString: "jdbc:mysql://localhost:3306/test",
 					},
 				},
+				Mappers: []Mapper{
+					{
+						Resource: "org/mybatis/example/BlogMapper.xml",
+					},
+					{
+						Class: "org.mybatis.example.PostMapper",
+					},
+					{
+						Package: "org.mybatis.builder",
+					},
+				},
+				TypeAliases: []TypeAlias{
+					{
+						Alias: "Blog",
+						Type:  "org.mybatis.example.Blog",
+					},
+					{
+						Package: "org.mybatis.domain",
+					},
+				},
 			},
 		},
+		{
+			configuration: `
+<configuration>
+	<typeAliases>
+	<typeAlias alias="Blog" type="org.mybatis.example.Blog"/>
+	</typeAliases>
+	<mappers>
+	<mapper resource="org/mybatis/example/BlogMapper.xml"/>
+	</mappers>
+</configuration>
+`,
+			want: &Configuration{
+				Mappers: []Mapper{
+					{
+						Resource: "org/mybatis/example/BlogMapper.xml",
+					},
+				},
+				TypeAliases: []TypeAlias{
+					{
+						Alias: "Blog",
+						Type:  "org.mybatis.example.Blog",
+					},
+				},
+			},
+		},
+		{
+			configuration: `<configuration><settings/></configuration>`,
+			want:          nil,
+		},
 	}
 	for _, tc := range testCases {
 		got, err := ParseConfiguration(tc.configuration)
@@ -0,0 +1,101 @@
+package configuration
+
+import (
+	"strings"
+)
+
+// builtinTypeAliases is the type aliases registered by mybatis TypeAliasRegistry, the keys are in lower case.
+// https://github.com/mybatis/mybatis-3/blob/master/src/main/java/org/apache/ibatis/type/TypeAliasRegistry.java
+var builtinTypeAliases = func() map[string]string {
+	aliases := map[string]string{
+		"map":        "java.util.Map",
+		"hashmap":    "java.util.HashMap",
+		"list":       "java.util.List",
+		"arraylist":  "java.util.ArrayList",
+		"collection": "java.util.Collection",
+		"iterator":   "java.util.Iterator",
+		"resultset":  "java.sql.ResultSet",
+	}
+	// The aliases of the types and their arrays, likes "int" and "int[]".
+	for alias, javaType := range map[string]string{
+		"string":     "java.lang.String",
+		"byte":       "java.lang.Byte",
+		"char":       "java.lang.Character",
+		"character":  "java.lang.Character",
+		"long":       "java.lang.Long",
+		"short":      "java.lang.Short",
+		"int":        "java.lang.Integer",
+		"integer":    "java.lang.Integer",
+		"double":     "java.lang.Double",
+		"float":      "java.lang.Float",
+		"boolean":    "java.lang.Boolean",
+		"date":       "java.util.Date",
+		"decimal":    "java.math.BigDecimal",
+		"bigdecimal": "java.math.BigDecimal",
+		"biginteger": "java.math.BigInteger",
+		"object":     "java.lang.Object",
+	} {
+		aliases[alias] = javaType
+		aliases[alias+"[]"] = javaType + "[]"
+	}
+	// The aliases of the primitive types and their arrays, likes "_int" and "_int[]".
+	for alias, javaType := range map[string]string{
+		"_byte":      "byte",
+		"_char":      "char",
+		"_character": "char",
+		"_long":      "long",
+		"_short":     "short",
+		"_int":       "int",
+		"_integer":   "int",
+		"_double":    "double",
+		"_float":     "float",
+		"_boolean":   "boolean",
+	} {
+		aliases[alias] = javaType
+		aliases[alias+"[]"] = javaType + "[]"
+	}
+	return aliases
+}()
+
+// TypeAliasResolver resolves the type aliases appearing in the mapper attributes, likes parameterType and resultType,
+// into the fully qualified class names.
+type TypeAliasResolver struct {
+	// aliases is the map from the lower case alias to the fully qualified class name.
+	aliases map[string]string
+}
+
+// NewTypeAliasResolver returns the resolver of the built-in type aliases and the type aliases declared in the
+// configuration, the configuration can be nil. The type alias without the alias attribute is registered by the simple
+// name of the class. The aliases declared by package cannot be resolved, because the classes in the package are unknown.
+func NewTypeAliasResolver(conf *Configuration) *TypeAliasResolver {
+	aliases := make(map[string]string)
+	for alias, javaType := range builtinTypeAliases {
+		aliases[alias] = javaType
+	}
+	if conf != nil {
+		for _, typeAlias := range conf.TypeAliases {
+			javaType := strings.TrimSpace(typeAlias.Type)
+			if javaType == "" {
+				continue
+			}
+			alias := strings.TrimSpace(typeAlias.Alias)
+			if alias == "" {
+				// Mybatis registers the simple name of the class if the alias is not set, the @Alias annotation on the
+				// class is not recognized.
+				alias = javaType[strings.LastIndexAny(javaType, ".$")+1:]
+			}
+			aliases[strings.ToLower(alias)] = javaType
+		}
+	}
+	return &TypeAliasResolver{aliases: aliases}
+}
+
+// Resolve returns the fully qualified class name of the type alias, the alias is case-insensitive as mybatis does.
+// The name which is not an alias is returned as it is, it is usually the fully qualified class name already.
+func (r *TypeAliasResolver) Resolve(name string) string {
+	name = strings.TrimSpace(name)
+	if javaType, ok := r.aliases[strings.ToLower(name)]; ok {
+		return javaType
+	}
+	return name
+}
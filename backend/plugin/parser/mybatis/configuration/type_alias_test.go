@@ -0,0 +1,43 @@
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeAliasResolver(t *testing.T) {
+	resolver := NewTypeAliasResolver(&Configuration{
+		TypeAliases: []TypeAlias{
+			{Alias: "Blog", Type: "org.mybatis.example.Blog"},
+			// The declared alias overrides the built-in one.
+			{Alias: "date", Type: "java.time.LocalDate"},
+			{Package: "org.mybatis.domain"},
+			// The simple name of the class is registered without the alias.
+			{Type: "com.acme.domain.User"},
+			{Type: "com.acme.domain.Order$Item"},
+		},
+	})
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{name: "int", want: "java.lang.Integer"},
+		{name: "String", want: "java.lang.String"},
+		{name: "_int[]", want: "int[]"},
+		{name: "map", want: "java.util.Map"},
+		{name: "HashMap", want: "java.util.HashMap"},
+		{name: "blog", want: "org.mybatis.example.Blog"},
+		{name: "date", want: "java.time.LocalDate"},
+		{name: " org.mybatis.domain.Author ", want: "org.mybatis.domain.Author"},
+		{name: "Author", want: "Author"},
+		{name: "User", want: "com.acme.domain.User"},
+		{name: "user", want: "com.acme.domain.User"},
+		{name: "Item", want: "com.acme.domain.Order$Item"},
+		{name: "", want: ""},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.want, resolver.Resolve(tc.name), tc.name)
+	}
+	require.Equal(t, "java.util.Date", NewTypeAliasResolver(nil).Resolve("date"))
+}
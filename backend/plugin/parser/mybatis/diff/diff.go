@@ -0,0 +1,436 @@
+// Package diff compares the restored SQL of the mybatis mapper statements between two git revisions.
+package diff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/discovery"
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper"
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper/ast"
+)
+
+// Revision is the repository files at a git ref.
+type Revision interface {
+	// Ref returns the git ref of the revision, likes the branch name or the commit sha.
+	Ref() string
+	// ListFiles returns the paths of the files in the repository, the paths are relative to the repository root
+	// and separated by slash.
+	ListFiles(ctx context.Context) ([]string, error)
+	// ReadFile returns the content of the file.
+	ReadFile(ctx context.Context, path string) (string, error)
+}
+
+// ChangeType is the type of the statement change.
+type ChangeType string
+
+const (
+	// ChangeTypeAdded means the statement only exists in the head revision.
+	ChangeTypeAdded ChangeType = "added"
+	// ChangeTypeRemoved means the statement only exists in the base revision.
+	ChangeTypeRemoved ChangeType = "removed"
+	// ChangeTypeChanged means the restored SQL of the statement is changed.
+	ChangeTypeChanged ChangeType = "changed"
+)
+
+// StatementDiff is the change of the restored SQL of a statement, the statements are matched by namespace.id.
+type StatementDiff struct {
+	Type      ChangeType
+	Namespace string
+	ID        string
+	// OldPath and OldSQL are the mapper file and the restored SQL in the base revision, they are empty if the
+	// statement is added.
+	OldPath string
+	OldSQL  string
+	// NewPath and NewSQL are the mapper file and the restored SQL in the head revision, they are empty if the
+	// statement is removed.
+	NewPath string
+	NewSQL  string
+}
+
+// FileError is the mapper file which cannot be read, parsed or restored in a revision. The statements which cannot
+// be restored in either revision are not compared, so a broken mapper file is not reported as the removed statements.
+type FileError struct {
+	Ref  string
+	Path string
+	// StatementID is the id of the statement which cannot be restored, it is empty if the whole file cannot be read
+	// or parsed.
+	StatementID string
+	Err         error
+}
+
+// Result is the result of the diff between two revisions.
+type Result struct {
+	// Statements are the changes of the statements sorted by namespace.id.
+	Statements []*StatementDiff
+	// FileErrors are the failures of the mapper files in the base revision followed by the head revision, sorted by
+	// the path in each revision.
+	FileErrors []*FileError
+}
+
+// statement is the restored statement in a revision.
+type statement struct {
+	path string
+	sql  string
+}
+
+// revisionStatements is the restored statements in a revision.
+type revisionStatements struct {
+	// statements is keyed by namespace.id.
+	statements map[string]*statement
+	// failedPaths are the mapper files which cannot be read or parsed.
+	failedPaths map[string]bool
+	// failedKeys are the statements which cannot be restored, keyed by namespace.id.
+	failedKeys map[string]bool
+	fileErrors []*FileError
+}
+
+// compared returns true if the statement in the other revision can be compared with this revision, i.e. neither the
+// statement nor the mapper file declaring it failed in this revision.
+func (r *revisionStatements) compared(key, path string) bool {
+	return !r.failedKeys[key] && !r.failedPaths[path]
+}
+
+// Diff returns the changes of the restored SQL of the statements from the base revision to the head revision. The
+// mapper files are discovered by discovery.Discover, and the parameters are restored to "?". The statements whose
+// SQL differs only in the whitespaces are not changed, so reformatting the mapper xml is not reported. The mapper
+// files which cannot be read, parsed or restored are reported in the FileErrors and the other files are compared.
+//
+// The changedPaths are the files changed from the base revision to the head revision, likes the files of the pull
+// request. The files not in the changedPaths are read only at the head revision and shared with the base revision,
+// so the base revision only reads the changed files. If the changedPaths is nil, the changes are unknown and the xml
+// files are read at both revisions.
+func Diff(ctx context.Context, base, head Revision, changedPaths []string) (*Result, error) {
+	headReader := newFileReader(head, nil, nil)
+	headStatements, err := restoreRevision(ctx, headReader)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to restore the statements at %q", head.Ref())
+	}
+	var baseReader *fileReader
+	if changedPaths == nil {
+		baseReader = newFileReader(base, nil, nil)
+	} else {
+		baseReader = newFileReader(base, headReader, changedPaths)
+	}
+	baseStatements, err := restoreRevision(ctx, baseReader)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to restore the statements at %q", base.Ref())
+	}
+
+	result := &Result{}
+	for key, oldStatement := range baseStatements.statements {
+		namespace, id := splitKey(key)
+		newStatement, ok := headStatements.statements[key]
+		if !ok {
+			if headStatements.compared(key, oldStatement.path) {
+				result.Statements = append(result.Statements, &StatementDiff{Type: ChangeTypeRemoved, Namespace: namespace, ID: id, OldPath: oldStatement.path, OldSQL: oldStatement.sql})
+			}
+			continue
+		}
+		if collapseWhitespaces(oldStatement.sql) == collapseWhitespaces(newStatement.sql) {
+			continue
+		}
+		result.Statements = append(result.Statements, &StatementDiff{Type: ChangeTypeChanged, Namespace: namespace, ID: id, OldPath: oldStatement.path, OldSQL: oldStatement.sql, NewPath: newStatement.path, NewSQL: newStatement.sql})
+	}
+	for key, newStatement := range headStatements.statements {
+		if _, ok := baseStatements.statements[key]; ok {
+			continue
+		}
+		if !baseStatements.compared(key, newStatement.path) {
+			continue
+		}
+		namespace, id := splitKey(key)
+		result.Statements = append(result.Statements, &StatementDiff{Type: ChangeTypeAdded, Namespace: namespace, ID: id, NewPath: newStatement.path, NewSQL: newStatement.sql})
+	}
+	sort.Slice(result.Statements, func(i, j int) bool {
+		if result.Statements[i].Namespace != result.Statements[j].Namespace {
+			return result.Statements[i].Namespace < result.Statements[j].Namespace
+		}
+		return result.Statements[i].ID < result.Statements[j].ID
+	})
+	result.FileErrors = append(result.FileErrors, baseStatements.fileErrors...)
+	result.FileErrors = append(result.FileErrors, headStatements.fileErrors...)
+	return result, nil
+}
+
+// fileReader reads the files of a revision at most once, the contents of the mapper files are cached so they are not
+// read again after the discovery, and can be shared with the other revision.
+type fileReader struct {
+	revision Revision
+	// contents are the contents of the files read, only the mapper files are kept after the discovery.
+	contents map[string]string
+	// read are the files read successfully, including the ones dropped from the contents.
+	read map[string]bool
+	// shared is the reader of the other revision whose unchanged files are shared with this revision, it is nil if
+	// the files are not shared.
+	shared  *fileReader
+	changed map[string]bool
+}
+
+func newFileReader(revision Revision, shared *fileReader, changedPaths []string) *fileReader {
+	changed := make(map[string]bool)
+	for _, path := range changedPaths {
+		changed[path] = true
+	}
+	return &fileReader{
+		revision: revision,
+		contents: make(map[string]string),
+		read:     make(map[string]bool),
+		shared:   shared,
+		changed:  changed,
+	}
+}
+
+func (r *fileReader) readFile(ctx context.Context, path string) (string, error) {
+	if content, ok := r.contents[path]; ok {
+		return content, nil
+	}
+	if r.shared != nil && !r.changed[path] && r.shared.read[path] {
+		// The unchanged file is the same in both revisions. If it is dropped by the other revision, it is not a mapper
+		// and the empty content is not a mapper either.
+		return r.shared.contents[path], nil
+	}
+	content, err := r.revision.ReadFile(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	r.contents[path] = content
+	r.read[path] = true
+	return content, nil
+}
+
+// retain drops the contents of the files which are not the mapper files.
+func (r *fileReader) retain(mapperFiles []*discovery.MapperFile) {
+	mapperPaths := make(map[string]bool)
+	for _, mapperFile := range mapperFiles {
+		mapperPaths[mapperFile.Path] = true
+	}
+	for path := range r.contents {
+		if !mapperPaths[path] {
+			delete(r.contents, path)
+		}
+	}
+}
+
+// restoreRevision restores the statements of the mapper files in the revision, the key is namespace.id. If the
+// statement is declared in multiple files, the first one in the path order wins. Only listing the files fails the
+// revision, the failures of the mapper files are recorded in the fileErrors.
+func restoreRevision(ctx context.Context, reader *fileReader) (*revisionStatements, error) {
+	revision := reader.revision
+	paths, err := revision.ListFiles(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list files")
+	}
+	result := &revisionStatements{
+		statements:  make(map[string]*statement),
+		failedPaths: make(map[string]bool),
+		failedKeys:  make(map[string]bool),
+	}
+	addFileError := func(path, statementID string, err error) {
+		result.fileErrors = append(result.fileErrors, &FileError{Ref: revision.Ref(), Path: path, StatementID: statementID, Err: err})
+	}
+	mapperFiles, err := discovery.Discover(paths, func(path string) (string, error) {
+		content, err := reader.readFile(ctx, path)
+		if err != nil {
+			// The unreadable file is reported and skipped, its root element is unknown.
+			addFileError(path, "", errors.Wrapf(err, "failed to read file %q", path))
+			result.failedPaths[path] = true
+			return "", nil
+		}
+		return content, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	reader.retain(mapperFiles)
+
+	type mapperInFile struct {
+		path string
+		node *ast.MapperNode
+	}
+	var mappers []*mapperInFile
+	// namespacedSQLMap is the sql fragments of all the mappers keyed by namespace.id, so the statements can include the
+	// fragments of the other mappers.
+	namespacedSQLMap := make(map[string]*ast.SQLNode)
+	for _, mapperFile := range mapperFiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		content, err := reader.readFile(ctx, mapperFile.Path)
+		if err != nil {
+			addFileError(mapperFile.Path, "", errors.Wrapf(err, "failed to read mapper file %q", mapperFile.Path))
+			result.failedPaths[mapperFile.Path] = true
+			continue
+		}
+		root, err := mapper.NewParser(content).Parse()
+		if err != nil {
+			addFileError(mapperFile.Path, "", errors.Wrapf(err, "failed to parse mapper file %q", mapperFile.Path))
+			result.failedPaths[mapperFile.Path] = true
+			continue
+		}
+		for _, child := range root.Children {
+			mapperNode, ok := child.(*ast.MapperNode)
+			if !ok {
+				continue
+			}
+			mappers = append(mappers, &mapperInFile{path: mapperFile.Path, node: mapperNode})
+			for _, node := range mapperNode.Children {
+				if sqlNode, ok := node.(*ast.SQLNode); ok {
+					namespacedSQLMap[mapperNode.Namespace+"."+sqlNode.ID] = sqlNode
+				}
+			}
+		}
+	}
+
+	for _, m := range mappers {
+		sqlMap := make(map[string]*ast.SQLNode)
+		for key, sqlNode := range namespacedSQLMap {
+			sqlMap[key] = sqlNode
+		}
+		for _, node := range m.node.Children {
+			if sqlNode, ok := node.(*ast.SQLNode); ok {
+				sqlMap[sqlNode.ID] = sqlNode
+			}
+		}
+		for _, node := range m.node.Children {
+			queryNode, ok := node.(*ast.QueryNode)
+			if !ok {
+				continue
+			}
+			key := m.node.Namespace + "." + queryNode.ID
+			if _, ok := result.statements[key]; ok || result.failedKeys[key] {
+				continue
+			}
+			restoreContext := &ast.RestoreContext{
+				SQLMap:                           sqlMap,
+				Variable:                         make(map[string]string),
+				SQLLastLineToOriginalLineMapping: make(map[int]int),
+				CurrentLastLine:                  1,
+				RestoreDataNodePlaceholder:       "?",
+				CurrentNamespace:                 m.node.Namespace,
+			}
+			var sb strings.Builder
+			if err := queryNode.RestoreSQL(restoreContext, &sb); err != nil {
+				addFileError(m.path, queryNode.ID, errors.Wrapf(err, "failed to restore statement %q in mapper file %q", key, m.path))
+				result.failedKeys[key] = true
+				continue
+			}
+			result.statements[key] = &statement{
+				path: m.path,
+				sql:  strings.TrimSpace(sb.String()),
+			}
+		}
+	}
+	sort.SliceStable(result.fileErrors, func(i, j int) bool {
+		return result.fileErrors[i].Path < result.fileErrors[j].Path
+	})
+	return result, nil
+}
+
+// splitKey splits namespace.id at the last dot, the statement id cannot contain dot.
+func splitKey(key string) (string, string) {
+	i := strings.LastIndex(key, ".")
+	return key[:i], key[i+1:]
+}
+
+func collapseWhitespaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// FormatMarkdown formats the diff result in markdown, which can be embedded in the description of the review
+// issue. The changed statements are shown as the line diff code blocks of the restored SQL, the unchanged lines are
+// shown as the context. The diffs are followed by the mapper files
+// which cannot be compared.
+func FormatMarkdown(result *Result) string {
+	var sb strings.Builder
+	if len(result.Statements) == 0 {
+		_, _ = sb.WriteString("No statement is changed.\n")
+	}
+	for _, d := range result.Statements {
+		path := d.NewPath
+		if d.Type == ChangeTypeRemoved {
+			path = d.OldPath
+		}
+		_, _ = fmt.Fprintf(&sb, "### %s `%s.%s`\n\n`%s`\n\n```diff\n", d.Type, d.Namespace, d.ID, path)
+		for _, line := range diffLines(splitLines(d.OldSQL), splitLines(d.NewSQL)) {
+			_, _ = fmt.Fprintf(&sb, "%c%s\n", line.op, line.text)
+		}
+		_, _ = sb.WriteString("```\n\n")
+	}
+	if len(result.FileErrors) > 0 {
+		if len(result.Statements) == 0 {
+			_, _ = sb.WriteString("\n")
+		}
+		_, _ = sb.WriteString("### failed to compare\n\n")
+		for _, fileError := range result.FileErrors {
+			_, _ = fmt.Fprintf(&sb, "- `%s` at `%s`: %v\n", fileError.Path, fileError.Ref, fileError.Err)
+		}
+		_, _ = sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// splitLines splits the SQL into the lines whose surrounding whitespaces are trimmed, the empty lines are removed.
+func splitLines(sql string) []string {
+	var lines []string
+	for _, line := range strings.Split(sql, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			lines = append(lines, trimmed)
+		}
+	}
+	return lines
+}
+
+// diffLine is a line of the line diff, the op is '-' for the removed line, '+' for the added line and ' ' for the
+// unchanged line.
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// diffLines returns the line diff from the old lines to the new lines by the longest common subsequence, the removed
+// lines are placed before the added lines at the same position.
+func diffLines(oldLines, newLines []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:].
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var result []diffLine
+	i, j := 0, 0
+	for i < len(oldLines) && j < len(newLines) {
+		switch {
+		case oldLines[i] == newLines[j]:
+			result = append(result, diffLine{op: ' ', text: oldLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, diffLine{op: '-', text: oldLines[i]})
+			i++
+		default:
+			result = append(result, diffLine{op: '+', text: newLines[j]})
+			j++
+		}
+	}
+	for ; i < len(oldLines); i++ {
+		result = append(result, diffLine{op: '-', text: oldLines[i]})
+	}
+	for ; j < len(newLines); j++ {
+		result = append(result, diffLine{op: '+', text: newLines[j]})
+	}
+	return result
+}
@@ -0,0 +1,185 @@
+package diff
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRevision is the revision whose files are in memory.
+type memoryRevision struct {
+	ref   string
+	files map[string]string
+	// reads are the paths read.
+	reads []string
+}
+
+func (r *memoryRevision) Ref() string {
+	return r.ref
+}
+
+func (r *memoryRevision) ListFiles(context.Context) ([]string, error) {
+	var paths []string
+	for path := range r.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (r *memoryRevision) ReadFile(_ context.Context, path string) (string, error) {
+	r.reads = append(r.reads, path)
+	content, ok := r.files[path]
+	if !ok {
+		return "", errors.Errorf("file %q not found", path)
+	}
+	return content, nil
+}
+
+func TestDiff(t *testing.T) {
+	base := &memoryRevision{
+		ref: "main",
+		files: map[string]string{
+			"src/main/resources/mapper/UserMapper.xml": `<mapper namespace="com.acme.UserMapper">
+	<sql id="columns">id, name</sql>
+	<select id="selectByID">SELECT <include refid="columns"/> FROM user WHERE id = #{id}</select>
+	<select id="selectAll">SELECT <include refid="com.acme.Common.all"/> FROM user</select>
+	<delete id="deleteByID">DELETE FROM user WHERE id = #{id}</delete>
+</mapper>`,
+			"src/main/resources/mapper/Common.xml": `<mapper namespace="com.acme.Common">
+	<sql id="all">*</sql>
+</mapper>`,
+			"pom.xml": `<project></project>`,
+		},
+	}
+	head := &memoryRevision{
+		ref: "feature",
+		files: map[string]string{
+			// The reformatting of selectByID is not reported, and the change of the shared fragment is reported.
+			"src/main/resources/mapper/UserMapper.xml": `<mapper namespace="com.acme.UserMapper">
+	<sql id="columns">id, name</sql>
+	<select id="selectByID">
+		SELECT <include refid="columns"/>
+		FROM user
+		WHERE id = #{id}
+	</select>
+	<select id="selectAll">SELECT <include refid="com.acme.Common.all"/> FROM user</select>
+	<update id="updateName">UPDATE user SET name = #{name} WHERE id = #{id}</update>
+</mapper>`,
+			"src/main/resources/mapper/Common.xml": `<mapper namespace="com.acme.Common">
+	<sql id="all">id, name, email</sql>
+</mapper>`,
+			"pom.xml": `<project></project>`,
+		},
+	}
+	result, err := Diff(context.Background(), base, head, nil)
+	require.NoError(t, err)
+	require.Empty(t, result.FileErrors)
+	// Each xml file is read once at each revision.
+	require.Len(t, base.reads, 3)
+	require.Len(t, head.reads, 3)
+
+	// With the changed files, the base revision only reads the changed files, and the result is the same.
+	base.reads, head.reads = nil, nil
+	changedResult, err := Diff(context.Background(), base, head, []string{"src/main/resources/mapper/UserMapper.xml", "src/main/resources/mapper/Common.xml"})
+	require.NoError(t, err)
+	require.Equal(t, result, changedResult)
+	require.ElementsMatch(t, []string{"src/main/resources/mapper/UserMapper.xml", "src/main/resources/mapper/Common.xml"}, base.reads)
+	require.Equal(t, []*StatementDiff{
+		{
+			Type:      ChangeTypeRemoved,
+			Namespace: "com.acme.UserMapper",
+			ID:        "deleteByID",
+			OldPath:   "src/main/resources/mapper/UserMapper.xml",
+			OldSQL:    "DELETE FROM user WHERE id = ?;",
+		},
+		{
+			Type:      ChangeTypeChanged,
+			Namespace: "com.acme.UserMapper",
+			ID:        "selectAll",
+			OldPath:   "src/main/resources/mapper/UserMapper.xml",
+			OldSQL:    "SELECT * FROM user;",
+			NewPath:   "src/main/resources/mapper/UserMapper.xml",
+			NewSQL:    "SELECT id, name, email FROM user;",
+		},
+		{
+			Type:      ChangeTypeAdded,
+			Namespace: "com.acme.UserMapper",
+			ID:        "updateName",
+			NewPath:   "src/main/resources/mapper/UserMapper.xml",
+			NewSQL:    "UPDATE user SET name = ? WHERE id = ?;",
+		},
+	}, result.Statements)
+
+	require.Equal(t, "### removed `com.acme.UserMapper.deleteByID`\n\n`src/main/resources/mapper/UserMapper.xml`\n\n```diff\n"+
+		"-DELETE FROM user WHERE id = ?;\n```\n\n"+
+		"### changed `com.acme.UserMapper.selectAll`\n\n`src/main/resources/mapper/UserMapper.xml`\n\n```diff\n"+
+		"-SELECT * FROM user;\n+SELECT id, name, email FROM user;\n```\n\n"+
+		"### added `com.acme.UserMapper.updateName`\n\n`src/main/resources/mapper/UserMapper.xml`\n\n```diff\n"+
+		"+UPDATE user SET name = ? WHERE id = ?;\n```\n\n", FormatMarkdown(result))
+	require.Equal(t, "No statement is changed.\n", FormatMarkdown(&Result{}))
+
+	// The broken mapper file is reported, the statements of the other files are still compared, and the statements
+	// of the broken file are not reported as removed.
+	head.files["src/main/resources/mapper/Common.xml"] = `<mapper namespace="com.acme.Common"><sql id="all">`
+	head.files["src/main/resources/mapper/OrderMapper.xml"] = `<mapper namespace="com.acme.OrderMapper">
+	<select id="selectAll">SELECT <include refid="com.acme.Common.all"/> FROM orders</select>
+	<select id="count">SELECT COUNT(*) FROM orders</select>
+</mapper>`
+	result, err = Diff(context.Background(), base, head, nil)
+	require.NoError(t, err)
+	require.Equal(t, []*StatementDiff{
+		{
+			Type:      ChangeTypeAdded,
+			Namespace: "com.acme.OrderMapper",
+			ID:        "count",
+			NewPath:   "src/main/resources/mapper/OrderMapper.xml",
+			NewSQL:    "SELECT COUNT(*) FROM orders;",
+		},
+		{
+			Type:      ChangeTypeRemoved,
+			Namespace: "com.acme.UserMapper",
+			ID:        "deleteByID",
+			OldPath:   "src/main/resources/mapper/UserMapper.xml",
+			OldSQL:    "DELETE FROM user WHERE id = ?;",
+		},
+		{
+			Type:      ChangeTypeAdded,
+			Namespace: "com.acme.UserMapper",
+			ID:        "updateName",
+			NewPath:   "src/main/resources/mapper/UserMapper.xml",
+			NewSQL:    "UPDATE user SET name = ? WHERE id = ?;",
+		},
+	}, result.Statements)
+	var fileErrors []string
+	for _, fileError := range result.FileErrors {
+		require.Equal(t, "feature", fileError.Ref)
+		fileErrors = append(fileErrors, fileError.Err.Error())
+	}
+	require.Len(t, fileErrors, 3)
+	require.Contains(t, fileErrors[0], `failed to parse mapper file "src/main/resources/mapper/Common.xml"`)
+	require.Contains(t, fileErrors[1], `failed to restore statement "com.acme.OrderMapper.selectAll"`)
+	require.Contains(t, fileErrors[2], `failed to restore statement "com.acme.UserMapper.selectAll"`)
+	require.Contains(t, FormatMarkdown(result), "### failed to compare\n\n- `src/main/resources/mapper/Common.xml` at `feature`: failed to parse mapper file")
+}
+
+func TestFormatMarkdown(t *testing.T) {
+	result := &Result{
+		Statements: []*StatementDiff{
+			{
+				Type:      ChangeTypeChanged,
+				Namespace: "com.acme.UserMapper",
+				ID:        "selectByName",
+				OldPath:   "src/main/resources/mapper/UserMapper.xml",
+				OldSQL:    "SELECT id, name\nFROM user\nWHERE name = ?\nORDER BY id;",
+				NewPath:   "src/main/resources/mapper/UserMapper.xml",
+				NewSQL:    "SELECT id, name, email\nFROM user\nWHERE name = ?\nAND deleted = 0\nORDER BY id;",
+			},
+		},
+	}
+	require.Equal(t, "### changed `com.acme.UserMapper.selectByName`\n\n`src/main/resources/mapper/UserMapper.xml`\n\n```diff\n"+
+		"-SELECT id, name\n+SELECT id, name, email\n FROM user\n WHERE name = ?\n+AND deleted = 0\n ORDER BY id;\n```\n\n", FormatMarkdown(result))
+}
@@ -0,0 +1,55 @@
+package diff
+
+import (
+	"context"
+
+	"github.com/bytebase/bytebase/backend/common"
+	"github.com/bytebase/bytebase/backend/plugin/vcs"
+)
+
+var (
+	_ Revision = (*vcsRevision)(nil)
+)
+
+// vcsRevision reads the repository files at the ref by the VCS provider.
+type vcsRevision struct {
+	provider     vcs.Provider
+	oauthCtx     *common.OauthContext
+	instanceURL  string
+	repositoryID string
+	refInfo      vcs.RefInfo
+}
+
+// NewVCSRevision returns the revision of the repository at the ref, the files are read by the VCS provider.
+func NewVCSRevision(provider vcs.Provider, oauthCtx *common.OauthContext, instanceURL, repositoryID string, refInfo vcs.RefInfo) Revision {
+	return &vcsRevision{
+		provider:     provider,
+		oauthCtx:     oauthCtx,
+		instanceURL:  instanceURL,
+		repositoryID: repositoryID,
+		refInfo:      refInfo,
+	}
+}
+
+// Ref implements Revision interface.
+func (r *vcsRevision) Ref() string {
+	return r.refInfo.RefName
+}
+
+// ListFiles implements Revision interface.
+func (r *vcsRevision) ListFiles(ctx context.Context) ([]string, error) {
+	nodes, err := r.provider.FetchRepositoryFileList(ctx, r.oauthCtx, r.instanceURL, r.repositoryID, r.refInfo.RefName, "")
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, node := range nodes {
+		paths = append(paths, node.Path)
+	}
+	return paths, nil
+}
+
+// ReadFile implements Revision interface.
+func (r *vcsRevision) ReadFile(ctx context.Context, path string) (string, error) {
+	return r.provider.ReadFileContent(ctx, r.oauthCtx, r.instanceURL, r.repositoryID, path, r.refInfo)
+}
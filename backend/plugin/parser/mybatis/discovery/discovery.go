@@ -0,0 +1,193 @@
+// Package discovery finds the mybatis mapper xml files in the repository without the explicit path patterns.
+package discovery
+
+import (
+	"context"
+	"encoding/xml"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/configuration"
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/lint"
+)
+
+// Reason is the reason why the file is discovered as the mapper xml.
+type Reason string
+
+const (
+	// ReasonConfiguration means the mapper is listed in the mappers of a mybatis configuration xml.
+	ReasonConfiguration Reason = "configuration"
+	// ReasonConventionalPath means the mapper is placed in the conventional location src/main/resources/**/mapper/*.xml.
+	ReasonConventionalPath Reason = "conventional-path"
+	// ReasonRootElement means the root element of the xml file is <mapper>.
+	ReasonRootElement Reason = "root-element"
+)
+
+// resourcesDirectory is the directory of the classpath resources in the maven and gradle projects.
+const resourcesDirectory = "src/main/resources/"
+
+// MapperFile is the discovered mybatis mapper xml file, the content is not kept, so discovering a large repository
+// does not hold all the mappers in memory. Use NewScanFunc to read the files lazily in the lint pipeline.
+type MapperFile struct {
+	Path   string
+	Reason Reason
+	// ConfigurationPath is the path of the mybatis configuration xml which lists the mapper, it is empty if the
+	// mapper is not listed in any configuration.
+	ConfigurationPath string
+}
+
+// ReadFileFunc reads the content of the file in the repository.
+type ReadFileFunc func(path string) (string, error)
+
+// Discover finds the mybatis mapper xml files in the repository files, the paths are relative to the repository root
+// and separated by slash. All the xml files are read to check the root element, and the files whose root element
+// is <mapper> are returned in the path order, the reason is the first one matched of:
+//
+//  1. listed in the <mappers> of a mybatis configuration xml, by the resource, the class or the package.
+//  2. placed in the conventional location src/main/resources/**/mapper/*.xml.
+//  3. the root element is <mapper>.
+func Discover(paths []string, readFile ReadFileFunc) ([]*MapperFile, error) {
+	mapperFiles := make(map[string]*MapperFile)
+	configurations := make(map[string]*configuration.Configuration)
+	for _, p := range paths {
+		if !strings.HasSuffix(p, ".xml") {
+			continue
+		}
+		content, err := readFile(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read file %q", p)
+		}
+		switch getRootElement(content) {
+		case "mapper":
+			reason := ReasonRootElement
+			if isConventionalMapperPath(p) {
+				reason = ReasonConventionalPath
+			}
+			mapperFiles[p] = &MapperFile{
+				Path:   p,
+				Reason: reason,
+			}
+		case "configuration":
+			conf, err := configuration.ParseConfiguration(content)
+			if err != nil {
+				// The invalid configuration does not prevent discovering the mappers by the other heuristics.
+				continue
+			}
+			if conf != nil {
+				configurations[p] = conf
+			}
+		}
+	}
+
+	// Iterate the configurations in the path order, so the first configuration listing the mapper wins.
+	var configurationPaths []string
+	for p := range configurations {
+		configurationPaths = append(configurationPaths, p)
+	}
+	sort.Strings(configurationPaths)
+	for _, configurationPath := range configurationPaths {
+		for _, mapperFile := range findConfiguredMappers(configurationPath, configurations[configurationPath], mapperFiles) {
+			if mapperFile.ConfigurationPath != "" {
+				continue
+			}
+			mapperFile.Reason = ReasonConfiguration
+			mapperFile.ConfigurationPath = configurationPath
+		}
+	}
+
+	var result []*MapperFile
+	for _, mapperFile := range mapperFiles {
+		result = append(result, mapperFile)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+// NewScanFunc returns the scanner of the lint pipeline which emits the discovered mapper files. The files are read
+// when they are emitted, so only the files queued in the pipeline are in memory.
+func NewScanFunc(mapperFiles []*MapperFile, readFile ReadFileFunc) lint.ScanFunc {
+	return func(ctx context.Context, emit func(file *lint.MapperFile) error) error {
+		for _, mapperFile := range mapperFiles {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			content, err := readFile(mapperFile.Path)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read file %q", mapperFile.Path)
+			}
+			if err := emit(&lint.MapperFile{Path: mapperFile.Path, Content: content}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// findConfiguredMappers returns the mapper files listed in the configuration. The resources, classes and packages are
+// resolved against the classpath root of the configuration, which is the src/main/resources directory containing
+// the configuration, or the directory of the configuration if it is not in the resources directory. The mappers
+// located by url are ignored because the urls are not in the repository.
+func findConfiguredMappers(configurationPath string, conf *configuration.Configuration, mapperFiles map[string]*MapperFile) []*MapperFile {
+	root := getClasspathRoot(configurationPath)
+	var result []*MapperFile
+	for _, mapper := range conf.Mappers {
+		switch {
+		case mapper.Resource != "":
+			if mapperFile, ok := mapperFiles[root+strings.TrimPrefix(mapper.Resource, "/")]; ok {
+				result = append(result, mapperFile)
+			}
+		case mapper.Class != "":
+			// The mapper xml of the mapper interface is placed in the same package with the same name.
+			if mapperFile, ok := mapperFiles[root+strings.ReplaceAll(mapper.Class, ".", "/")+".xml"]; ok {
+				result = append(result, mapperFile)
+			}
+		case mapper.Package != "":
+			dir := root + strings.ReplaceAll(mapper.Package, ".", "/")
+			for p, mapperFile := range mapperFiles {
+				if path.Dir(p) == dir {
+					result = append(result, mapperFile)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// getClasspathRoot returns the classpath root of the file with the trailing slash, or empty string for the repository root.
+func getClasspathRoot(p string) string {
+	if idx := strings.LastIndex("/"+p, "/"+resourcesDirectory); idx >= 0 {
+		return p[:idx+len(resourcesDirectory)]
+	}
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return dir + "/"
+}
+
+// isConventionalMapperPath returns true if the path matches src/main/resources/**/mapper/*.xml.
+func isConventionalMapperPath(p string) bool {
+	if !strings.HasPrefix(p, resourcesDirectory) && !strings.Contains(p, "/"+resourcesDirectory) {
+		return false
+	}
+	return path.Base(path.Dir(p)) == "mapper"
+}
+
+// getRootElement returns the local name of the root element of the xml, or empty string if the xml is invalid.
+func getRootElement(content string) string {
+	d := xml.NewDecoder(strings.NewReader(content))
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return ""
+		}
+		if startElement, ok := token.(xml.StartElement); ok {
+			return startElement.Name.Local
+		}
+	}
+}
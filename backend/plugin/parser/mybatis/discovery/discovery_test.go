@@ -0,0 +1,83 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/lint"
+)
+
+func TestDiscover(t *testing.T) {
+	const mapperXML = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE mapper PUBLIC "-//mybatis.org//DTD Mapper 3.0//EN" "http://mybatis.org/dtd/mybatis-3-mapper.dtd">
+<mapper namespace="com.acme.UserMapper"></mapper>`
+	files := map[string]string{
+		"user/src/main/resources/mybatis-config.xml": `<configuration>
+	<mappers>
+		<mapper resource="com/acme/UserMapper.xml"/>
+		<mapper class="com.acme.order.OrderMapper"/>
+		<package name="com.acme.item"/>
+		<mapper url="file:///var/mappers/AuthorMapper.xml"/>
+	</mappers>
+</configuration>`,
+		"user/src/main/resources/com/acme/UserMapper.xml":        mapperXML,
+		"user/src/main/resources/com/acme/order/OrderMapper.xml": mapperXML,
+		"user/src/main/resources/com/acme/item/ItemMapper.xml":   mapperXML,
+		"user/src/main/resources/com/acme/item/logback.xml":      `<configuration><appender name="STDOUT"/></configuration>`,
+		"blog/src/main/resources/mapper/BlogMapper.xml":          mapperXML,
+		"blog/src/main/resources/mapper/ehcache.xml":             `<ehcache></ehcache>`,
+		"legacy/sql/PostMapper.xml":                              mapperXML,
+		"legacy/sql/broken.xml":                                  `<mapper`,
+		"pom.xml":                                                `<project></project>`,
+		"README.md":                                              `<mapper></mapper>`,
+	}
+	var paths []string
+	for p := range files {
+		paths = append(paths, p)
+	}
+	mapperFiles, err := Discover(paths, func(p string) (string, error) {
+		return files[p], nil
+	})
+	require.NoError(t, err)
+
+	type result struct {
+		path              string
+		reason            Reason
+		configurationPath string
+	}
+	var results []result
+	for _, mapperFile := range mapperFiles {
+		results = append(results, result{
+			path:              mapperFile.Path,
+			reason:            mapperFile.Reason,
+			configurationPath: mapperFile.ConfigurationPath,
+		})
+	}
+	require.Equal(t, []result{
+		{path: "blog/src/main/resources/mapper/BlogMapper.xml", reason: ReasonConventionalPath},
+		{path: "legacy/sql/PostMapper.xml", reason: ReasonRootElement},
+		{path: "user/src/main/resources/com/acme/UserMapper.xml", reason: ReasonConfiguration, configurationPath: "user/src/main/resources/mybatis-config.xml"},
+		{path: "user/src/main/resources/com/acme/item/ItemMapper.xml", reason: ReasonConfiguration, configurationPath: "user/src/main/resources/mybatis-config.xml"},
+		{path: "user/src/main/resources/com/acme/order/OrderMapper.xml", reason: ReasonConfiguration, configurationPath: "user/src/main/resources/mybatis-config.xml"},
+	}, results)
+
+	// The discovered files are read lazily by the lint pipeline.
+	var scanned []string
+	err = NewScanFunc(mapperFiles, func(p string) (string, error) {
+		return files[p], nil
+	})(context.Background(), func(file *lint.MapperFile) error {
+		require.Equal(t, mapperXML, file.Content)
+		scanned = append(scanned, file.Path)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, scanned, len(mapperFiles))
+
+	_, err = Discover([]string{"a.xml"}, func(string) (string, error) {
+		return "", errors.New("not found")
+	})
+	require.ErrorContains(t, err, `failed to read file "a.xml": not found`)
+}
@@ -0,0 +1,284 @@
+// Package graph builds the dependency graph of the mybatis mapper statements, the sql fragments and the result maps,
+// so the statements affected by editing a shared fragment can be found before editing it.
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper/ast"
+)
+
+// NodeKind is the kind of the node in the dependency graph.
+type NodeKind string
+
+const (
+	// NodeKindStatement is the <select>, <insert>, <update> or <delete> statement.
+	NodeKindStatement NodeKind = "statement"
+	// NodeKindFragment is the <sql> fragment.
+	NodeKindFragment NodeKind = "fragment"
+	// NodeKindResultMap is the <resultMap>.
+	NodeKindResultMap NodeKind = "result-map"
+)
+
+// EdgeKind is the kind of the edge in the dependency graph.
+type EdgeKind string
+
+const (
+	// EdgeKindInclude means the statement or the fragment includes the fragment by <include>.
+	EdgeKindInclude EdgeKind = "include"
+	// EdgeKindResultMap means the statement references the result map by the resultMap attribute.
+	EdgeKindResultMap EdgeKind = "result-map"
+	// EdgeKindExtends means the result map extends the result map by the extends attribute.
+	EdgeKindExtends EdgeKind = "extends"
+)
+
+// NodeID returns the id of the node in the dependency graph likes "fragment:com.acme.UserMapper.columns", the kind
+// is a part of the id because the statement, the fragment and the result map can have the same namespace.id.
+func NodeID(kind NodeKind, namespacedID string) string {
+	return string(kind) + ":" + namespacedID
+}
+
+// Node is the node in the dependency graph.
+type Node struct {
+	// ID is the id returned by NodeID, it is unique across the mappers.
+	ID   string   `json:"id"`
+	Kind NodeKind `json:"kind"`
+	// Name is the namespace.id of the statement, the fragment or the result map.
+	Name string `json:"name"`
+	// Unresolved is true if the node is referenced but not declared in any mapper, or the reference contains the
+	// ${} property which cannot be resolved statically.
+	Unresolved bool `json:"unresolved,omitempty"`
+}
+
+// Edge is the dependency from a node to the node it references.
+type Edge struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+}
+
+// Graph is the dependency graph of the mappers, the nodes are sorted by id and the edges are sorted by from and to.
+type Graph struct {
+	Nodes []*Node `json:"nodes"`
+	Edges []*Edge `json:"edges"`
+	// Adjacency is the ids of the nodes referenced by each node, it is the same as the edges.
+	Adjacency map[string][]string `json:"adjacency"`
+}
+
+// Build builds the dependency graph of the mappers in the roots, the roots are usually the mapper files of a
+// repository. The <include> elements nested in the dynamic SQL elements are all counted.
+func Build(roots []*ast.RootNode) *Graph {
+	b := &builder{
+		nodes: make(map[string]*Node),
+		edges: make(map[Edge]bool),
+	}
+	// Declare the nodes first, so the references across mappers can be resolved regardless of the order.
+	for _, mapperNode := range getMapperNodes(roots) {
+		for _, node := range mapperNode.Children {
+			switch n := node.(type) {
+			case *ast.QueryNode:
+				b.declare(NodeKindStatement, qualify(mapperNode.Namespace, n.ID), false)
+			case *ast.SQLNode:
+				b.declare(NodeKindFragment, qualify(mapperNode.Namespace, n.ID), false)
+			case *ast.ResultMapNode:
+				b.declare(NodeKindResultMap, qualify(mapperNode.Namespace, n.ID), false)
+			}
+		}
+	}
+	for _, mapperNode := range getMapperNodes(roots) {
+		for _, node := range mapperNode.Children {
+			switch n := node.(type) {
+			case *ast.QueryNode:
+				from := NodeID(NodeKindStatement, qualify(mapperNode.Namespace, n.ID))
+				b.addIncludes(mapperNode.Namespace, from, n)
+				for _, resultMap := range strings.Split(n.ResultMap, ",") {
+					if resultMap = strings.TrimSpace(resultMap); resultMap != "" {
+						b.reference(mapperNode.Namespace, from, resultMap, NodeKindResultMap, EdgeKindResultMap)
+					}
+				}
+			case *ast.SQLNode:
+				b.addIncludes(mapperNode.Namespace, NodeID(NodeKindFragment, qualify(mapperNode.Namespace, n.ID)), n)
+			case *ast.ResultMapNode:
+				if extends := strings.TrimSpace(n.Extends); extends != "" {
+					b.reference(mapperNode.Namespace, NodeID(NodeKindResultMap, qualify(mapperNode.Namespace, n.ID)), extends, NodeKindResultMap, EdgeKindExtends)
+				}
+			}
+		}
+	}
+	return b.build()
+}
+
+type builder struct {
+	nodes map[string]*Node
+	edges map[Edge]bool
+}
+
+func (b *builder) declare(kind NodeKind, name string, unresolved bool) string {
+	id := NodeID(kind, name)
+	if _, ok := b.nodes[id]; !ok {
+		b.nodes[id] = &Node{ID: id, Kind: kind, Name: name, Unresolved: unresolved}
+	}
+	return id
+}
+
+// addIncludes adds the include edges of the <include> elements in the node, the fragment included by the <include>
+// is not visited, because the fragment has its own edges.
+func (b *builder) addIncludes(namespace string, from string, node ast.Node) {
+	for _, child := range ast.GetChildren(node) {
+		if includeNode, ok := child.(*ast.IncludeNode); ok {
+			b.reference(namespace, from, includeNode.RefID, NodeKindFragment, EdgeKindInclude)
+			continue
+		}
+		b.addIncludes(namespace, from, child)
+	}
+}
+
+// reference adds the edge from the node to the referenced node. The reference is resolved in the namespace first,
+// then as the namespace.id, the unresolved node is created if neither is declared. The reference which contains the ${}
+// property, likes refid="${dialect}.columns", is replaced by the configuration properties at runtime, so it is not
+// resolved and the unresolved node is created for it.
+func (b *builder) reference(namespace string, from string, ref string, kind NodeKind, edgeKind EdgeKind) {
+	if strings.Contains(ref, "${") {
+		to := b.declare(kind, qualify(namespace, ref), true)
+		b.edges[Edge{From: from, To: to, Kind: edgeKind}] = true
+		return
+	}
+	to := NodeID(kind, qualify(namespace, ref))
+	if _, ok := b.nodes[to]; !ok {
+		if _, ok := b.nodes[NodeID(kind, ref)]; ok {
+			to = NodeID(kind, ref)
+		} else {
+			to = b.declare(kind, qualify(namespace, ref), true)
+		}
+	}
+	b.edges[Edge{From: from, To: to, Kind: edgeKind}] = true
+}
+
+func (b *builder) build() *Graph {
+	g := &Graph{
+		Nodes:     []*Node{},
+		Edges:     []*Edge{},
+		Adjacency: make(map[string][]string),
+	}
+	for _, node := range b.nodes {
+		g.Nodes = append(g.Nodes, node)
+		g.Adjacency[node.ID] = []string{}
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	for edge := range b.edges {
+		edge := edge
+		g.Edges = append(g.Edges, &edge)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	for _, edge := range g.Edges {
+		g.Adjacency[edge.From] = append(g.Adjacency[edge.From], edge.To)
+	}
+	return g
+}
+
+// Dependents returns the sorted ids of the statements which depend on the node directly or indirectly, for example,
+// the statements which include the fragment, or include the fragments including the fragment. It is the blast radius
+// of editing the node.
+func (g *Graph) Dependents(id string) []string {
+	reverse := make(map[string][]string)
+	for _, edge := range g.Edges {
+		reverse[edge.To] = append(reverse[edge.To], edge.From)
+	}
+	kinds := make(map[string]NodeKind)
+	for _, node := range g.Nodes {
+		kinds[node.ID] = node.Kind
+	}
+	visited := map[string]bool{id: true}
+	queue := []string{id}
+	dependents := []string{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, from := range reverse[current] {
+			if visited[from] {
+				continue
+			}
+			visited[from] = true
+			queue = append(queue, from)
+			if kinds[from] == NodeKindStatement {
+				dependents = append(dependents, from)
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// JSON returns the graph in JSON, which has the nodes, the edges and the adjacency.
+func (g *Graph) JSON() ([]byte, error) {
+	b, err := json.Marshal(g)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal dependency graph")
+	}
+	return b, nil
+}
+
+// DOT returns the graph in the Graphviz DOT language, the statements are rounded boxes, the fragments are boxes, the
+// result maps are ellipses, and the unresolved nodes are dashed.
+func (g *Graph) DOT() string {
+	var sb strings.Builder
+	_, _ = sb.WriteString("digraph mybatis {\n")
+	for _, node := range g.Nodes {
+		var attributes, styles []string
+		switch node.Kind {
+		case NodeKindStatement:
+			attributes = append(attributes, "shape=box")
+			styles = append(styles, "rounded")
+		case NodeKindFragment:
+			attributes = append(attributes, "shape=box")
+		case NodeKindResultMap:
+			attributes = append(attributes, "shape=ellipse")
+		}
+		if node.Unresolved {
+			styles = append(styles, "dashed")
+		}
+		// Graphviz keeps the last style attribute only, so the styles are joined into one.
+		if len(styles) > 0 {
+			attributes = append(attributes, fmt.Sprintf("style=%q", strings.Join(styles, ",")))
+		}
+		attributes = append(attributes, fmt.Sprintf("label=%q", node.Name))
+		_, _ = fmt.Fprintf(&sb, "  %q [%s];\n", node.ID, strings.Join(attributes, ", "))
+	}
+	for _, edge := range g.Edges {
+		_, _ = fmt.Fprintf(&sb, "  %q -> %q [label=%q];\n", edge.From, edge.To, edge.Kind)
+	}
+	_, _ = sb.WriteString("}\n")
+	return sb.String()
+}
+
+func getMapperNodes(roots []*ast.RootNode) []*ast.MapperNode {
+	var mapperNodes []*ast.MapperNode
+	for _, root := range roots {
+		for _, child := range root.Children {
+			if mapperNode, ok := child.(*ast.MapperNode); ok {
+				mapperNodes = append(mapperNodes, mapperNode)
+			}
+		}
+	}
+	return mapperNodes
+}
+
+// qualify returns the namespace.id of the id in the namespace.
+func qualify(namespace string, id string) string {
+	if namespace == "" {
+		return id
+	}
+	return namespace + "." + id
+}
@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper"
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper/ast"
+)
+
+func TestBuild(t *testing.T) {
+	var roots []*ast.RootNode
+	for _, xml := range []string{
+		`<mapper namespace="com.acme.UserMapper">
+	<resultMap id="baseResult" type="User"><id property="id" column="id"/></resultMap>
+	<resultMap id="detailResult" type="User" extends="baseResult"><result property="email" column="email"/></resultMap>
+	<sql id="columns">id, name, <include refid="com.acme.Common.auditColumns"/></sql>
+	<select id="selectByID" resultMap="detailResult">
+		SELECT <include refid="columns"/> FROM user
+		<where><if test="id != null">id = #{id}</if></where>
+	</select>
+	<select id="selectAll" resultMap="baseResult">
+		SELECT <include refid="com.acme.Common.auditColumns"/> FROM user <include refid="missing"/>
+	</select>
+	<delete id="deleteByID">DELETE FROM user WHERE id = #{id}</delete>
+</mapper>`,
+		`<mapper namespace="com.acme.Common">
+	<sql id="auditColumns">created_at, updated_at</sql>
+</mapper>`,
+	} {
+		root, err := mapper.NewParser(xml).Parse()
+		require.NoError(t, err)
+		roots = append(roots, root)
+	}
+	g := Build(roots)
+	require.Equal(t, []*Node{
+		{ID: "fragment:com.acme.Common.auditColumns", Kind: NodeKindFragment, Name: "com.acme.Common.auditColumns"},
+		{ID: "fragment:com.acme.UserMapper.columns", Kind: NodeKindFragment, Name: "com.acme.UserMapper.columns"},
+		{ID: "fragment:com.acme.UserMapper.missing", Kind: NodeKindFragment, Name: "com.acme.UserMapper.missing", Unresolved: true},
+		{ID: "result-map:com.acme.UserMapper.baseResult", Kind: NodeKindResultMap, Name: "com.acme.UserMapper.baseResult"},
+		{ID: "result-map:com.acme.UserMapper.detailResult", Kind: NodeKindResultMap, Name: "com.acme.UserMapper.detailResult"},
+		{ID: "statement:com.acme.UserMapper.deleteByID", Kind: NodeKindStatement, Name: "com.acme.UserMapper.deleteByID"},
+		{ID: "statement:com.acme.UserMapper.selectAll", Kind: NodeKindStatement, Name: "com.acme.UserMapper.selectAll"},
+		{ID: "statement:com.acme.UserMapper.selectByID", Kind: NodeKindStatement, Name: "com.acme.UserMapper.selectByID"},
+	}, g.Nodes)
+	require.Equal(t, []*Edge{
+		{From: "fragment:com.acme.UserMapper.columns", To: "fragment:com.acme.Common.auditColumns", Kind: EdgeKindInclude},
+		{From: "result-map:com.acme.UserMapper.detailResult", To: "result-map:com.acme.UserMapper.baseResult", Kind: EdgeKindExtends},
+		{From: "statement:com.acme.UserMapper.selectAll", To: "fragment:com.acme.Common.auditColumns", Kind: EdgeKindInclude},
+		{From: "statement:com.acme.UserMapper.selectAll", To: "fragment:com.acme.UserMapper.missing", Kind: EdgeKindInclude},
+		{From: "statement:com.acme.UserMapper.selectAll", To: "result-map:com.acme.UserMapper.baseResult", Kind: EdgeKindResultMap},
+		{From: "statement:com.acme.UserMapper.selectByID", To: "fragment:com.acme.UserMapper.columns", Kind: EdgeKindInclude},
+		{From: "statement:com.acme.UserMapper.selectByID", To: "result-map:com.acme.UserMapper.detailResult", Kind: EdgeKindResultMap},
+	}, g.Edges)
+	require.Equal(t, []string{}, g.Adjacency["statement:com.acme.UserMapper.deleteByID"])
+	require.Equal(t, []string{"fragment:com.acme.Common.auditColumns"}, g.Adjacency["fragment:com.acme.UserMapper.columns"])
+
+	// Both statements are affected by editing the shared fragment, one of them includes it indirectly.
+	require.Equal(t, []string{
+		"statement:com.acme.UserMapper.selectAll",
+		"statement:com.acme.UserMapper.selectByID",
+	}, g.Dependents(NodeID(NodeKindFragment, "com.acme.Common.auditColumns")))
+	require.Equal(t, []string{
+		"statement:com.acme.UserMapper.selectAll",
+		"statement:com.acme.UserMapper.selectByID",
+	}, g.Dependents(NodeID(NodeKindResultMap, "com.acme.UserMapper.baseResult")))
+	require.Equal(t, []string{}, g.Dependents(NodeID(NodeKindStatement, "com.acme.UserMapper.deleteByID")))
+
+	b, err := g.JSON()
+	require.NoError(t, err)
+	require.Contains(t, string(b), `{"from":"fragment:com.acme.UserMapper.columns","to":"fragment:com.acme.Common.auditColumns","kind":"include"}`)
+	require.Contains(t, string(b), `{"id":"fragment:com.acme.UserMapper.missing","kind":"fragment","name":"com.acme.UserMapper.missing","unresolved":true}`)
+
+	dot := g.DOT()
+	require.Contains(t, dot, `  "fragment:com.acme.UserMapper.missing" [shape=box, style="dashed", label="com.acme.UserMapper.missing"];`)
+	require.Contains(t, dot, `  "statement:com.acme.UserMapper.deleteByID" [shape=box, style="rounded", label="com.acme.UserMapper.deleteByID"];`)
+	require.Contains(t, dot, `  "statement:com.acme.UserMapper.selectByID" -> "fragment:com.acme.UserMapper.columns" [label="include"];`)
+}
+
+func TestBuildPropertyReference(t *testing.T) {
+	root, err := mapper.NewParser(`<mapper namespace="com.acme.UserMapper">
+	<sql id="columns">id, name</sql>
+	<select id="selectAll">SELECT <include refid="${dialect}.columns"/>, <include refid="${columns}"/> FROM user</select>
+</mapper>`).Parse()
+	require.NoError(t, err)
+	g := Build([]*ast.RootNode{root})
+	// The references are replaced by the configuration properties at runtime, they are not resolved statically.
+	require.Equal(t, []*Node{
+		{ID: "fragment:com.acme.UserMapper.${columns}", Kind: NodeKindFragment, Name: "com.acme.UserMapper.${columns}", Unresolved: true},
+		{ID: "fragment:com.acme.UserMapper.${dialect}.columns", Kind: NodeKindFragment, Name: "com.acme.UserMapper.${dialect}.columns", Unresolved: true},
+		{ID: "fragment:com.acme.UserMapper.columns", Kind: NodeKindFragment, Name: "com.acme.UserMapper.columns"},
+		{ID: "statement:com.acme.UserMapper.selectAll", Kind: NodeKindStatement, Name: "com.acme.UserMapper.selectAll"},
+	}, g.Nodes)
+	require.Equal(t, []string{
+		"fragment:com.acme.UserMapper.${columns}",
+		"fragment:com.acme.UserMapper.${dialect}.columns",
+	}, g.Adjacency["statement:com.acme.UserMapper.selectAll"])
+}
@@ -0,0 +1,146 @@
+package lint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Baseline is the record of the pre-existing findings, the findings in the baseline are reported as existing
+// and only the new findings fail the check. The findings are keyed by the rule and the statement Fingerprint,
+// so the baseline is not affected by moving the statements or reformatting the mapper xml. The statements which cannot
+// be restored have no SQL fingerprint, their findings are keyed by the digest over the namespace.id and the raw body
+// of the statement instead, see Finding.Fingerprint.
+//
+//	entries:
+//	  - rule: mybatis.no-dollar-substitution
+//	    fingerprint: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+//	    statement: com.acme.UserMapper.selectByFilter
+//	    count: 2
+type Baseline struct {
+	Entries []*BaselineEntry `yaml:"entries"`
+}
+
+// BaselineEntry is the findings of a rule on the statements with the same fingerprint.
+type BaselineEntry struct {
+	Rule        RuleType `yaml:"rule"`
+	Fingerprint string   `yaml:"fingerprint"`
+	// Statement is the namespace and id of the statement when the baseline is generated, it is only for reading.
+	Statement string `yaml:"statement,omitempty"`
+	// Count is the number of the findings.
+	Count int `yaml:"count"`
+}
+
+type baselineKey struct {
+	rule        RuleType
+	fingerprint string
+}
+
+// getBaselineKey returns the key of the finding in the baseline. The finding which is not reported by Check may have
+// no fingerprint, it is keyed by the digest over the namespace.id and the content, so it can be loaded back.
+func getBaselineKey(finding *Finding) baselineKey {
+	fingerprint := finding.Fingerprint
+	if fingerprint == "" {
+		digest := sha256.Sum256([]byte(finding.Namespace + "." + finding.StatementID + "\n" + finding.Content))
+		fingerprint = hex.EncodeToString(digest[:])
+	}
+	return baselineKey{rule: finding.Rule, fingerprint: fingerprint}
+}
+
+// NewBaseline generates the baseline from the findings, the suppressed findings are not recorded.
+func NewBaseline(findings []*Finding) *Baseline {
+	entries := make(map[baselineKey]*BaselineEntry)
+	for _, finding := range findings {
+		if finding.Suppressed {
+			continue
+		}
+		key := getBaselineKey(finding)
+		entry, ok := entries[key]
+		if !ok {
+			entry = &BaselineEntry{
+				Rule:        key.rule,
+				Fingerprint: key.fingerprint,
+				Statement:   finding.Namespace + "." + finding.StatementID,
+			}
+			entries[key] = entry
+		}
+		entry.Count++
+	}
+	baseline := &Baseline{}
+	for _, entry := range entries {
+		baseline.Entries = append(baseline.Entries, entry)
+	}
+	// Sort the entries to make the baseline file stable.
+	sort.Slice(baseline.Entries, func(i, j int) bool {
+		if baseline.Entries[i].Statement != baseline.Entries[j].Statement {
+			return baseline.Entries[i].Statement < baseline.Entries[j].Statement
+		}
+		if baseline.Entries[i].Rule != baseline.Entries[j].Rule {
+			return baseline.Entries[i].Rule < baseline.Entries[j].Rule
+		}
+		return baseline.Entries[i].Fingerprint < baseline.Entries[j].Fingerprint
+	})
+	return baseline
+}
+
+// UnmarshalBaseline unmarshals the baseline from the YAML content.
+func UnmarshalBaseline(content string) (*Baseline, error) {
+	var baseline Baseline
+	if err := yaml.Unmarshal([]byte(content), &baseline); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal mapper lint baseline")
+	}
+	for _, entry := range baseline.Entries {
+		if entry.Rule == "" || entry.Fingerprint == "" {
+			return nil, errors.Errorf("the rule and fingerprint of baseline entry are required")
+		}
+		if entry.Count <= 0 {
+			return nil, errors.Errorf("the count of baseline entry for rule %q and fingerprint %q must be positive", entry.Rule, entry.Fingerprint)
+		}
+	}
+	return &baseline, nil
+}
+
+// Marshal marshals the baseline into the YAML content.
+func (b *Baseline) Marshal() (string, error) {
+	content, err := yaml.Marshal(b)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to marshal mapper lint baseline")
+	}
+	return string(content), nil
+}
+
+// Apply marks the findings recorded in the baseline as existing. If there are more findings than the count
+// recorded in the baseline for the same rule and fingerprint, the rest of them are new findings.
+func (b *Baseline) Apply(findings []*Finding) {
+	remaining := make(map[baselineKey]int)
+	for _, entry := range b.Entries {
+		remaining[baselineKey{rule: entry.Rule, fingerprint: entry.Fingerprint}] += entry.Count
+	}
+	for _, finding := range findings {
+		if finding.Suppressed {
+			continue
+		}
+		key := getBaselineKey(finding)
+		if remaining[key] <= 0 {
+			continue
+		}
+		remaining[key]--
+		finding.Existing = true
+	}
+}
+
+// GetNewFindings returns the findings which are neither suppressed nor existing in the baseline, only these findings
+// should fail the check.
+func GetNewFindings(findings []*Finding) []*Finding {
+	var result []*Finding
+	for _, finding := range findings {
+		if finding.Suppressed || finding.Existing {
+			continue
+		}
+		result = append(result, finding)
+	}
+	return result
+}
@@ -0,0 +1,118 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	storepb "github.com/bytebase/bytebase/proto/generated-go/store"
+)
+
+func TestBaseline(t *testing.T) {
+	ruleList := []*storepb.SQLReviewRule{
+		{
+			Type:  string(RuleNoDollarSubstitution),
+			Level: storepb.SQLReviewRuleLevel_ERROR,
+		},
+	}
+	oldXML := `<mapper namespace="com.bytebase.test">
+	<select id="selectByOrder">
+		SELECT * FROM t ORDER BY ${orderBy}
+	</select>
+	<select id="selectByName">
+		SELECT * FROM t WHERE name = ${name} AND category = ${category}
+	</select>
+</mapper>`
+	baseline := NewBaseline(runCheck(t, oldXML, ruleList))
+	content, err := baseline.Marshal()
+	require.NoError(t, err)
+	baseline, err = UnmarshalBaseline(content)
+	require.NoError(t, err)
+	require.Len(t, baseline.Entries, 2)
+	require.Equal(t, "com.bytebase.test.selectByName", baseline.Entries[0].Statement)
+	require.Equal(t, 2, baseline.Entries[0].Count)
+
+	// The statements are moved and reformatted, and a new statement with ${} is added.
+	newXML := `<mapper namespace="com.bytebase.test">
+	<select id="selectByNewFilter">
+		SELECT * FROM t WHERE price = ${price}
+	</select>
+	<select id="selectByName">
+		SELECT *
+		FROM t
+		WHERE name = ${name}
+		AND category = ${category}
+	</select>
+	<!-- Comments do not change the fingerprint. -->
+	<select id="selectByOrder">SELECT * FROM t ORDER BY ${orderBy}</select>
+</mapper>`
+	findings := runCheck(t, newXML, ruleList)
+	baseline.Apply(findings)
+	newFindings := GetNewFindings(findings)
+	require.Len(t, findings, 4)
+	require.Len(t, newFindings, 1)
+	require.Equal(t, "selectByNewFilter", newFindings[0].StatementID)
+
+	// The extra findings than recorded in the baseline are new findings.
+	moreXML := `<mapper namespace="com.bytebase.test">
+	<select id="selectByOrder">
+		SELECT * FROM t ORDER BY ${orderBy}
+	</select>
+	<select id="selectByOrderCopy">
+		SELECT * FROM t ORDER BY ${orderBy}
+	</select>
+</mapper>`
+	findings = runCheck(t, moreXML, ruleList)
+	baseline.Apply(findings)
+	newFindings = GetNewFindings(findings)
+	require.Len(t, newFindings, 1)
+	require.Equal(t, "selectByOrderCopy", newFindings[0].StatementID)
+
+	_, err = UnmarshalBaseline(`
+entries:
+  - rule: mybatis.no-dollar-substitution
+    fingerprint: abc
+    count: 0
+`)
+	require.Error(t, err)
+}
+
+func TestBaselineOfUnrestoredStatements(t *testing.T) {
+	ruleList := []*storepb.SQLReviewRule{
+		{
+			Type:  string(RuleNoDollarSubstitution),
+			Level: storepb.SQLReviewRuleLevel_ERROR,
+		},
+	}
+	xml := `<mapper namespace="com.bytebase.test">
+	<!-- bb:bogus -->
+	<select id="selectByOrder">
+		SELECT * FROM t ORDER BY ${orderBy} <include refid="missing"/>
+	</select>
+	<select id="selectByName">
+		SELECT * FROM t WHERE name = ${name} <include refid="missing"/>
+	</select>
+</mapper>`
+	findings := runCheck(t, xml, ruleList)
+	require.Len(t, findings, 5)
+	for _, finding := range findings {
+		require.NotEmpty(t, finding.Fingerprint)
+	}
+	content, err := NewBaseline(findings).Marshal()
+	require.NoError(t, err)
+	baseline, err := UnmarshalBaseline(content)
+	require.NoError(t, err)
+	// The statements which cannot be restored are not keyed by the same empty fingerprint.
+	require.Len(t, baseline.Entries, 5)
+
+	// The grandfathered statement does not hide the new statement which cannot be restored either.
+	findings = runCheck(t, xml[:len(xml)-len("</mapper>")]+`	<select id="selectByEmail">
+		SELECT * FROM t WHERE email = ${email} <include refid="missing"/>
+	</select>
+</mapper>`, ruleList)
+	baseline.Apply(findings)
+	newFindings := GetNewFindings(findings)
+	require.Len(t, newFindings, 2)
+	require.Equal(t, "selectByEmail", newFindings[0].StatementID)
+	require.Equal(t, "selectByEmail", newFindings[1].StatementID)
+}
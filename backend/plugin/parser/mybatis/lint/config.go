@@ -0,0 +1,146 @@
+package lint
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bytebase/bytebase/backend/plugin/advisor"
+	storepb "github.com/bytebase/bytebase/proto/generated-go/store"
+)
+
+// MergeSQLReviewRules merges the SQL review config override in the format of sql-review-override.yml into the rule
+// list of the SQL review policy, so the mapper lint rules are tuned the same way as the SQL review rules, likes:
+//
+//	template: bb.sql-review.prod
+//	ruleList:
+//	  - type: mybatis.no-dollar-substitution
+//	    level: WARNING
+//	  - type: mybatis.require-timeout
+//	    payload:
+//	      max-referenced-tables: 5
+//
+// The user-defined rules are all of the type "mybatis.cel-expression", they are distinguished by the name in the
+// payload, so the override of them takes the type "mybatis.cel-expression/<name>", likes:
+//
+//	ruleList:
+//	  - type: mybatis.cel-expression/orders-require-limit
+//	    level: ERROR
+//
+// The SQL review policy is attached to the environment, and the override is usually kept in the repository of the
+// project, so merging them overrides the mapper lint rules per project and per environment.
+//
+// If the template of the override is set, the SQL review rules are merged by advisor.MergeSQLReviewRules, otherwise
+// the SQL review rules in the rule list are kept. The mapper lint rules are not in the templates, they are merged
+// into the mapper lint rules in the rule list the same way as advisor.MergeSQLReviewRules: the level is overridden
+// if it is "ERROR", "WARNING" or "DISABLED", the payload keys are overridden, and the comment is replaced. The mapper
+// lint rule which is not in the rule list is appended, its level defaults to "WARNING", and the name of the appended
+// user-defined rule is set in the payload.
+func MergeSQLReviewRules(ruleList []*storepb.SQLReviewRule, override *advisor.SQLReviewConfigOverride) ([]*storepb.SQLReviewRule, error) {
+	overrides := make(map[RuleType]*advisor.SQLReviewRuleData)
+	var orderedRules []RuleType
+	for _, rule := range override.RuleList {
+		identity := RuleType(rule.Type)
+		if identity == RuleCELExpression {
+			return nil, errors.Errorf("the override of rule %q must specify the name of the rule, likes %q", rule.Type, RuleCELExpression+"/<name>")
+		}
+		if _, _, ok := parseRuleIdentity(identity); !ok {
+			continue
+		}
+		if _, ok := overrides[identity]; !ok {
+			orderedRules = append(orderedRules, identity)
+		}
+		overrides[identity] = rule
+	}
+
+	var result []*storepb.SQLReviewRule
+	if override.Template != "" {
+		templateRules, err := advisor.MergeSQLReviewRules(override)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, templateRules...)
+	}
+	merged := make(map[RuleType]bool)
+	for _, rule := range ruleList {
+		ruleType := RuleType(rule.Type)
+		if _, ok := getRule(ruleType); !ok {
+			if override.Template == "" {
+				result = append(result, rule)
+			}
+			continue
+		}
+		params, err := unmarshalRuleParams(rule.Payload)
+		if err != nil {
+			return nil, err
+		}
+		identity, err := getRuleIdentity(ruleType, params)
+		if err != nil {
+			return nil, err
+		}
+		mergedRule, err := mergeRule(rule, overrides[identity])
+		if err != nil {
+			return nil, err
+		}
+		merged[identity] = true
+		result = append(result, mergedRule)
+	}
+	for _, identity := range orderedRules {
+		if merged[identity] {
+			continue
+		}
+		ruleType, name, _ := parseRuleIdentity(identity)
+		rule := &storepb.SQLReviewRule{
+			Type:  string(ruleType),
+			Level: storepb.SQLReviewRuleLevel_WARNING,
+		}
+		if name != "" {
+			payload, err := json.Marshal(map[string]string{"name": name})
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to marshal payload of rule %q", identity)
+			}
+			rule.Payload = string(payload)
+		}
+		mergedRule, err := mergeRule(rule, overrides[identity])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, mergedRule)
+	}
+	return result, nil
+}
+
+// mergeRule returns the copy of the mapper lint rule with the override merged, the rule is returned as is if the
+// override is nil.
+func mergeRule(rule *storepb.SQLReviewRule, override *advisor.SQLReviewRuleData) (*storepb.SQLReviewRule, error) {
+	if override == nil {
+		return rule, nil
+	}
+	merged, ok := proto.Clone(rule).(*storepb.SQLReviewRule)
+	if !ok {
+		return nil, errors.Errorf("failed to clone rule %q", rule.Type)
+	}
+	switch override.Level {
+	case "ERROR", "WARNING", "DISABLED":
+		merged.Level = storepb.SQLReviewRuleLevel(storepb.SQLReviewRuleLevel_value[override.Level])
+	}
+	merged.Comment = override.Comment
+	if len(override.Payload) > 0 {
+		payload := make(map[string]any)
+		if rule.Payload != "" {
+			if err := json.Unmarshal([]byte(rule.Payload), &payload); err != nil {
+				return nil, errors.Wrapf(err, "failed to unmarshal payload of rule %q", rule.Type)
+			}
+		}
+		for key, value := range override.Payload {
+			payload[key] = value
+		}
+		bytes, err := json.Marshal(payload)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal payload of rule %q", rule.Type)
+		}
+		merged.Payload = string(bytes)
+	}
+	return merged, nil
+}
@@ -0,0 +1,162 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bytebase/bytebase/backend/plugin/advisor"
+	storepb "github.com/bytebase/bytebase/proto/generated-go/store"
+)
+
+func TestMergeSQLReviewRules(t *testing.T) {
+	// The rule list of the SQL review policy of the environment.
+	ruleList := []*storepb.SQLReviewRule{
+		{
+			Type:  string(RuleNoDollarSubstitution),
+			Level: storepb.SQLReviewRuleLevel_ERROR,
+		},
+		{
+			Type:    string(RuleRequireTimeout),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"check-no-limit":true,"max-referenced-tables":3}`,
+		},
+		{
+			Type:  "statement.select.no-select-all",
+			Level: storepb.SQLReviewRuleLevel_WARNING,
+		},
+	}
+
+	unmarshal := func(content string) *advisor.SQLReviewConfigOverride {
+		override := &advisor.SQLReviewConfigOverride{}
+		require.NoError(t, yaml.Unmarshal([]byte(content), override))
+		return override
+	}
+
+	// The override of the project.
+	got, err := MergeSQLReviewRules(ruleList, unmarshal(`
+ruleList:
+  - type: mybatis.no-dollar-substitution
+    level: DISABLED
+    comment: the legacy project uses ${} for the dynamic table names
+  - type: mybatis.require-timeout
+    level: TEST
+    payload:
+      max-referenced-tables: 5
+  - type: mybatis.test.in-list-limit
+    payload:
+      max-in-list: 100
+  - type: statement.select.no-select-all
+    level: ERROR
+`))
+	require.NoError(t, err)
+	type rule struct {
+		Type    string
+		Level   storepb.SQLReviewRuleLevel
+		Comment string
+		Payload string
+	}
+	var rules []rule
+	for _, r := range got {
+		rules = append(rules, rule{Type: r.Type, Level: r.Level, Comment: r.Comment, Payload: r.Payload})
+	}
+	require.Equal(t, []rule{
+		{
+			Type:    string(RuleNoDollarSubstitution),
+			Level:   storepb.SQLReviewRuleLevel_DISABLED,
+			Comment: "the legacy project uses ${} for the dynamic table names",
+		},
+		{
+			Type:    string(RuleRequireTimeout),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"check-no-limit":true,"max-referenced-tables":5}`,
+		},
+		// The SQL review rules are kept without the template.
+		{
+			Type:  "statement.select.no-select-all",
+			Level: storepb.SQLReviewRuleLevel_WARNING,
+		},
+		{
+			Type:    string(ruleTestInListLimit),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"max-in-list":100}`,
+		},
+	}, rules)
+	// The rule list of the policy should not be changed.
+	require.Equal(t, storepb.SQLReviewRuleLevel_ERROR, ruleList[0].Level)
+	require.Equal(t, `{"check-no-limit":true,"max-referenced-tables":3}`, ruleList[1].Payload)
+
+	// With the template, the SQL review rules are merged from the template, and the mapper lint rules are kept.
+	got, err = MergeSQLReviewRules(ruleList, unmarshal(`
+template: bb.sql-review.prod
+ruleList:
+  - type: statement.select.no-select-all
+    level: DISABLED
+  - type: mybatis.no-dollar-substitution
+    level: WARNING
+`))
+	require.NoError(t, err)
+	levels := make(map[string]storepb.SQLReviewRuleLevel)
+	for _, rule := range got {
+		levels[rule.Type] = rule.Level
+	}
+	require.Equal(t, storepb.SQLReviewRuleLevel_DISABLED, levels["statement.select.no-select-all"])
+	require.Equal(t, storepb.SQLReviewRuleLevel_WARNING, levels[string(RuleNoDollarSubstitution)])
+	require.Equal(t, storepb.SQLReviewRuleLevel_WARNING, levels[string(RuleRequireTimeout)])
+	require.Greater(t, len(got), len(ruleList))
+
+	_, err = MergeSQLReviewRules(ruleList, unmarshal(`template: bb.sql-review.unknown`))
+	require.ErrorContains(t, err, "cannot find the template")
+
+	// The user-defined rules are overridden separately by the name.
+	celRuleList := []*storepb.SQLReviewRule{
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"expression":"true","name":"a"}`,
+		},
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"expression":"false","name":"b"}`,
+		},
+	}
+	got, err = MergeSQLReviewRules(celRuleList, unmarshal(`
+ruleList:
+  - type: mybatis.cel-expression/b
+    level: ERROR
+  - type: mybatis.cel-expression/c
+    payload:
+      expression: statement.timeout == 0
+`))
+	require.NoError(t, err)
+	rules = nil
+	for _, r := range got {
+		rules = append(rules, rule{Type: r.Type, Level: r.Level, Comment: r.Comment, Payload: r.Payload})
+	}
+	require.Equal(t, []rule{
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"expression":"true","name":"a"}`,
+		},
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_ERROR,
+			Payload: `{"expression":"false","name":"b"}`,
+		},
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"expression":"statement.timeout == 0","name":"c"}`,
+		},
+	}, rules)
+
+	_, err = MergeSQLReviewRules(celRuleList, unmarshal(`
+ruleList:
+  - type: mybatis.cel-expression
+    level: ERROR
+`))
+	require.ErrorContains(t, err, "must specify the name of the rule")
+}
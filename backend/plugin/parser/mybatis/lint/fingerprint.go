@@ -0,0 +1,92 @@
+package lint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper/ast"
+	storepb "github.com/bytebase/bytebase/proto/generated-go/store"
+)
+
+// FingerprintOptions is the options of computing the statement fingerprint.
+type FingerprintOptions struct {
+	// IgnoreLiterals makes the fingerprint insensitive to the literals and the length of IN lists, so the statement
+	// whose literals differ between branches, for example, "status = 1" on one branch and "status = 2" on another,
+	// has the same fingerprint. The restored SQL is normalized by NormalizeSQL if true.
+	IgnoreLiterals bool
+	// Engine is the engine of the database which the statement runs against, it is used to choose the
+	// engine-specific normalizer if IgnoreLiterals is true.
+	Engine storepb.Engine
+}
+
+// Fingerprint returns the stable fingerprint of the statement with the default options.
+// See FingerprintOptions.Fingerprint for details.
+func Fingerprint(statement *StatementMetadata) string {
+	return FingerprintOptions{}.Fingerprint(statement)
+}
+
+// Fingerprint returns the stable fingerprint of the statement, which is the hex encoded SHA-256 digest over the
+// normalized restored SQL. The namespace, id and line of the statement are not part of the fingerprint, and the
+// whitespaces, comments and keyword case are normalized, so the fingerprint is stable across renaming, moving and
+// reformatting the statement. It is used as the key of the baseline, history tracking and workload correlation.
+func (o FingerprintOptions) Fingerprint(statement *StatementMetadata) string {
+	digest := sha256.Sum256([]byte(o.normalize(statement.SQL)))
+	return hex.EncodeToString(digest[:])
+}
+
+func (o FingerprintOptions) normalize(sql string) string {
+	if !o.IgnoreLiterals {
+		return strings.Join(normalizeTokens(scanSQL(sql), false /* stripLiterals */), " ")
+	}
+	return normalizeSQLWithFallback(o.Engine, sql)
+}
+
+// fallbackFingerprint returns the fingerprint of the statement which cannot be restored, it is the hex encoded
+// SHA-256 digest over the namespace.id and the raw body of the statement. Unlike Fingerprint, it changes if the
+// statement is renamed, but it is stable across moving the statement, and it is unique across the statements.
+func fallbackFingerprint(namespace string, statement *ast.QueryNode) string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "%s.%s\n", namespace, statement.ID)
+	writeRawBody(&sb, statement)
+	digest := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(digest[:])
+}
+
+// writeRawBody writes the body of the node as it is declared, the included sql fragments are not expanded.
+func writeRawBody(sb *strings.Builder, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.TextNode:
+		_, _ = sb.WriteString(n.Text)
+	case *ast.ParameterNode:
+		_, _ = fmt.Fprintf(sb, "#{%s}", n.Name)
+	case *ast.VariableNode:
+		_, _ = fmt.Fprintf(sb, "${%s}", n.Name)
+	case *ast.ScriptNode:
+		_, _ = sb.WriteString(n.Text)
+	case *ast.IncludeNode:
+		_, _ = fmt.Fprintf(sb, "<include refid=%q>", n.RefID)
+		for _, property := range n.PropertyChildren {
+			_, _ = fmt.Fprintf(sb, "<property name=%q value=%q>", property.Name, property.Value)
+		}
+	case *ast.IfNode:
+		_, _ = fmt.Fprintf(sb, "<if test=%q>", n.Test)
+	case *ast.WhenNode:
+		_, _ = fmt.Fprintf(sb, "<when test=%q>", n.Test)
+	case *ast.QueryNode, *ast.DataNode:
+	default:
+		_, _ = fmt.Fprintf(sb, "<%T>", n)
+	}
+	for _, child := range ast.GetChildren(node) {
+		writeRawBody(sb, child)
+		_, _ = sb.WriteString(" ")
+	}
+}
+
+// invalidDirectiveFingerprint returns the fingerprint of the invalid directive finding, it is the hex encoded
+// SHA-256 digest over the namespace and the content of the finding, so it is stable across moving the directive.
+func invalidDirectiveFingerprint(namespace string, content string) string {
+	digest := sha256.Sum256([]byte(namespace + "\n" + content))
+	return hex.EncodeToString(digest[:])
+}
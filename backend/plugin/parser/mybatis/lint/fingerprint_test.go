@@ -0,0 +1,44 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	storepb "github.com/bytebase/bytebase/proto/generated-go/store"
+)
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := Fingerprint(&StatementMetadata{
+		Namespace: "com.bytebase.test",
+		ID:        "selectActive",
+		SQL:       "SELECT * FROM t WHERE status = 1 AND name = 'a'",
+	})
+	// Renaming and reformatting do not change the fingerprint.
+	require.Equal(t, fingerprint, Fingerprint(&StatementMetadata{
+		Namespace: "com.bytebase.renamed",
+		ID:        "selectEnabled",
+		Line:      10,
+		SQL:       "SELECT *\n\tFROM t /* filter */\n\tWHERE status = 1\n\tAND name = 'a'",
+	}))
+	// The literals are part of the fingerprint by default.
+	literalChanged := &StatementMetadata{
+		SQL: "SELECT * FROM t WHERE status = 2 AND name = 'b'",
+	}
+	require.NotEqual(t, fingerprint, Fingerprint(literalChanged))
+
+	options := FingerprintOptions{IgnoreLiterals: true}
+	require.Equal(t, options.Fingerprint(&StatementMetadata{
+		SQL: "SELECT * FROM t WHERE status = 1 AND name = 'a'",
+	}), options.Fingerprint(literalChanged))
+	require.NotEqual(t, options.Fingerprint(literalChanged), options.Fingerprint(&StatementMetadata{
+		SQL: "SELECT * FROM t WHERE status = 2 OR name = 'b'",
+	}))
+	// The keyword case and the length of IN lists do not change the fingerprint with the engine-specific normalizer.
+	options = FingerprintOptions{IgnoreLiterals: true, Engine: storepb.Engine_MYSQL}
+	require.Equal(t, options.Fingerprint(&StatementMetadata{
+		SQL: "SELECT * FROM t WHERE id IN (1, 2)",
+	}), options.Fingerprint(&StatementMetadata{
+		SQL: "select * from t where id in (3, 4, 5)",
+	}))
+}
@@ -0,0 +1,118 @@
+package lint
+
+// sqlKeywords is the common keywords of the SQL dialects, which are case-insensitive in all the engines. The words
+// not in the list are treated as the identifiers, whose case sensitivity depends on the engine.
+var sqlKeywords = map[string]bool{
+	"ADD":   true,
+	"ALL":   true,
+	"ALTER": true,
+	"AND":   true,
+	"ANY":   true,
+	"AS":    true,
+	"ASC":   true,
+	"AVG":   true,
+
+	"BETWEEN": true,
+	"BY":      true,
+
+	"CASE":     true,
+	"CAST":     true,
+	"COALESCE": true,
+	"COLUMN":   true,
+	"COUNT":    true,
+	"CREATE":   true,
+	"CROSS":    true,
+
+	"DEFAULT":   true,
+	"DELETE":    true,
+	"DESC":      true,
+	"DISTINCT":  true,
+	"DROP":      true,
+	"DUPLICATE": true,
+
+	"ELSE":   true,
+	"END":    true,
+	"ESCAPE": true,
+	"EXCEPT": true,
+	"EXISTS": true,
+
+	"FALSE":  true,
+	"FETCH":  true,
+	"FIRST":  true,
+	"FOR":    true,
+	"FROM":   true,
+	"FULL":   true,
+	"GROUP":  true,
+	"HAVING": true,
+
+	"IGNORE":    true,
+	"ILIKE":     true,
+	"IN":        true,
+	"INDEX":     true,
+	"INNER":     true,
+	"INSERT":    true,
+	"INTERSECT": true,
+	"INTO":      true,
+	"IS":        true,
+
+	"JOIN": true,
+	"KEY":  true,
+
+	"LEFT":  true,
+	"LIKE":  true,
+	"LIMIT": true,
+
+	"MAX":   true,
+	"MERGE": true,
+	"MIN":   true,
+	"MINUS": true,
+
+	"NATURAL": true,
+	"NEXT":    true,
+	"NOT":     true,
+	"NULL":    true,
+	"NULLS":   true,
+
+	"OFFSET": true,
+	"ON":     true,
+	"ONLY":   true,
+	"OR":     true,
+	"ORDER":  true,
+	"OUTER":  true,
+	"OVER":   true,
+
+	"PARTITION": true,
+	"PRIMARY":   true,
+
+	"RECURSIVE": true,
+	"REPLACE":   true,
+	"RETURNING": true,
+	"RIGHT":     true,
+	"ROW":       true,
+	"ROWS":      true,
+
+	"SELECT":        true,
+	"SET":           true,
+	"SOME":          true,
+	"STRAIGHT_JOIN": true,
+	"SUM":           true,
+
+	"TABLE":    true,
+	"THEN":     true,
+	"TOP":      true,
+	"TRUE":     true,
+	"TRUNCATE": true,
+
+	"UNION":  true,
+	"UNIQUE": true,
+	"UPDATE": true,
+	"USING":  true,
+
+	"VALUES": true,
+	"VIEW":   true,
+
+	"WHEN":   true,
+	"WHERE":  true,
+	"WINDOW": true,
+	"WITH":   true,
+}
@@ -0,0 +1,561 @@
+// Package lint defines the lint rules for mybatis mapper xml.
+//
+// How to add a mapper lint rule:
+//  1. Implement the Rule interface, the rule receives the statement AST, the statement metadata and the
+//     database catalog in the Context.
+//  2. Register the rule with a unique RuleType by Register in the init function of the package, the built-in
+//     rules are in this package, and the organization-specific rules can be in any package imported by the server.
+//  3. Add the rule to the rule list of the SQL review policy with the RuleType as the rule type.
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/bytebase/bytebase/backend/plugin/advisor/catalog"
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/configuration"
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper/ast"
+	storepb "github.com/bytebase/bytebase/proto/generated-go/store"
+)
+
+// RuleType is the type of the mapper lint rule.
+type RuleType string
+
+const (
+	// RuleNoDollarSubstitution disallows the ${} substitution in the statement, because it is vulnerable to SQL injection.
+	RuleNoDollarSubstitution RuleType = "mybatis.no-dollar-substitution"
+	// RuleCELExpression is the user-defined rule written in CEL expression over the statement metadata. The findings
+	// of the user-defined rule are reported as "mybatis.cel-expression/<name>", see CELExpressionRule.
+	RuleCELExpression RuleType = "mybatis.cel-expression"
+	// RuleRequireTimeout requires the timeout attribute on the expensive statements, so the runaway queries are bounded.
+	RuleRequireTimeout RuleType = "mybatis.require-timeout"
+	// RuleDuplicateStatement reports the statements whose normalized SQL is the same as a statement declared before it
+	// in the mapper, they are usually copy-pasted and can be merged.
+	RuleDuplicateStatement RuleType = "mybatis.duplicate-statement"
+	// RuleExpansionLimit is the diagnostic reported if restoring the statement exceeds the include depth or output size
+	// limit, the rules based on the restored SQL are skipped for the statement. It is always reported at the ERROR level.
+	RuleExpansionLimit RuleType = "mybatis.expansion-limit"
+	// RuleInvalidDirective is the diagnostic reported for the xml comment which looks like a bytebase directive but
+	// is malformed, the comment is ignored. It is always reported at the WARNING level.
+	RuleInvalidDirective RuleType = "mybatis.invalid-directive"
+	// RuleRestoreFailure is the diagnostic reported if the statement cannot be restored, for example, the included
+	// fragment is not found. The rules based on the restored SQL are skipped for the statement. It is always reported
+	// at the WARNING level.
+	RuleRestoreFailure RuleType = "mybatis.restore-failure"
+)
+
+// Finding is the problem found by the mapper lint rule.
+type Finding struct {
+	// Rule is the type of the rule which reports the finding.
+	Rule RuleType
+	// Level is the level of the rule which reports the finding.
+	Level storepb.SQLReviewRuleLevel
+	// Namespace is the namespace of the mapper which contains the statement.
+	Namespace string
+	// StatementID is the id of the statement.
+	StatementID string
+	// Line is the line of the statement in mybatis mapper xml.
+	Line    int
+	Title   string
+	Content string
+	// Node is the node which causes the finding, nil means the finding is for the whole statement.
+	Node ast.Node
+
+	// Suppressed is true if the finding is suppressed by the bb:ignore directive.
+	Suppressed bool
+	// SuppressReason is the reason given in the bb:ignore directive.
+	SuppressReason string
+
+	// Overrides is the rule parameters read by the rule which are overridden by the bb:config directive placed above the statement.
+	Overrides map[string]string
+
+	// Fingerprint is the fingerprint of the statement, it is the stable key of the finding in the baseline. It is the
+	// digest over the namespace.id and the raw body of the statement if the statement cannot be restored, and the
+	// digest over the namespace and the content if the finding is of the invalid directive.
+	Fingerprint string
+	// Existing is true if the finding is recorded in the baseline, the existing findings should not fail the check.
+	Existing bool
+}
+
+// Context is the context for checking a statement with a mapper lint rule.
+type Context struct {
+	// Rule is the SQL review rule of the mapper lint rule, the level and payload are defined in it.
+	Rule *storepb.SQLReviewRule
+	// Namespace is the namespace of the mapper which contains the statement.
+	Namespace string
+	// Mapper is the mapper node which contains the statement.
+	Mapper *ast.MapperNode
+	// Statement is the statement to check.
+	Statement *ast.QueryNode
+	// SQLMap is the map of sql fragments in the mapper, key is the id of sql element.
+	SQLMap map[string]*ast.SQLNode
+	// ParameterMaps is the map of the legacy parameter maps in the mapper, key is either the id or the namespace.id
+	// of the parameterMap element, because the statement can reference it by both.
+	ParameterMaps map[string]*ast.ParameterMapNode
+	// Metadata is the metadata of the statement.
+	Metadata *StatementMetadata
+	// MapperMetadata is the metadata of all the statements in the mapper in the declaration order, including the
+	// statement to check.
+	MapperMetadata []*StatementMetadata
+	// Catalog is the catalog of the database which the statement runs against, it is nil if the schema is unknown.
+	Catalog *catalog.Finder
+
+	// params is the rule parameters defined in the payload of the rule.
+	params map[string]string
+	// overrides is the rule parameters defined in the bb:config directives placed above the statement.
+	overrides map[string]string
+	// usedOverrides is the overrides which are read by the rule.
+	usedOverrides map[string]string
+	// fingerprintOptions is the options of computing the statement fingerprint.
+	fingerprintOptions FingerprintOptions
+	// maxIncludeDepth and maxOutputSize are the expansion limits of restoring the statement.
+	maxIncludeDepth int
+	maxOutputSize   int
+	// typeAliasResolver resolves the type aliases in the statement attributes.
+	typeAliasResolver *configuration.TypeAliasResolver
+	// restoreErr is the reason why the statement cannot be restored. It is set before building the metadata if the SQL
+	// restored from the file exceeds the limit, otherwise it is set by newStatementMetadata if restoring fails.
+	restoreErr error
+}
+
+// Param returns the value of the rule parameter, the parameter defined in the bb:config directive placed above the statement
+// takes precedence over the one defined in the rule payload.
+func (ctx *Context) Param(name string) (string, bool) {
+	if value, ok := ctx.overrides[name]; ok {
+		ctx.usedOverrides[name] = value
+		return value, true
+	}
+	value, ok := ctx.params[name]
+	return value, ok
+}
+
+// unmarshalRuleParams unmarshals the payload of the mapper lint rule, the payload is a JSON object
+// whose keys are the parameter names. The non-string values are kept in the JSON format.
+func unmarshalRuleParams(payload string) (map[string]string, error) {
+	params := make(map[string]string)
+	if len(payload) == 0 {
+		return params, nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(payload), &raw); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal mapper lint rule payload %q", payload)
+	}
+	for name, value := range raw {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			params[name] = s
+			continue
+		}
+		params[name] = string(value)
+	}
+	return params, nil
+}
+
+// WalkFunc is the function called for each node visited by Walk. The path is the list of nodes from the statement
+// to the parent of the node, and the properties are the include properties which are visible to the node.
+type WalkFunc func(node ast.Node, path []ast.Node, properties map[string]string) error
+
+// Walk walks the statement in depth-first order and calls fn for each node, the sql fragments referenced by include
+// elements are expanded in place. The sql fragments which cannot be found or are included recursively are skipped.
+func (ctx *Context) Walk(fn WalkFunc) error {
+	return walk(ctx.Statement, nil, make(map[string]string), make(map[*ast.SQLNode]bool), ctx.SQLMap, fn)
+}
+
+var propertyCatcher = regexp.MustCompile(`\${([a-zA-Z0-9_]+)}`)
+
+func walk(node ast.Node, path []ast.Node, properties map[string]string, visiting map[*ast.SQLNode]bool, sqlMap map[string]*ast.SQLNode, fn WalkFunc) error {
+	if err := fn(node, path, properties); err != nil {
+		return err
+	}
+	// Use the full slice expression to avoid the siblings sharing the same underlying array.
+	path = append(path[:len(path):len(path)], node)
+	if includeNode, ok := node.(*ast.IncludeNode); ok {
+		sqlNode, ok := sqlMap[replaceProperties(includeNode.RefID, properties)]
+		if !ok || visiting[sqlNode] {
+			return nil
+		}
+		includeProperties := make(map[string]string)
+		for k, v := range properties {
+			includeProperties[k] = v
+		}
+		for _, propertyNode := range includeNode.PropertyChildren {
+			includeProperties[propertyNode.Name] = replaceProperties(propertyNode.Value, properties)
+		}
+		visiting[sqlNode] = true
+		defer delete(visiting, sqlNode)
+		return walk(sqlNode, path, includeProperties, visiting, sqlMap, fn)
+	}
+	for _, child := range ast.GetChildren(node) {
+		if err := walk(child, path, properties, visiting, sqlMap, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceProperties replaces the ${name} in s with the value of the property.
+func replaceProperties(s string, properties map[string]string) string {
+	return propertyCatcher.ReplaceAllStringFunc(s, func(sub string) string {
+		matches := propertyCatcher.FindStringSubmatch(sub)
+		if len(matches) != 2 {
+			return sub
+		}
+		if value, ok := properties[matches[1]]; ok {
+			return value
+		}
+		return sub
+	})
+}
+
+// Rule is the interface for mapper lint rule.
+type Rule interface {
+	// Check checks the statement in the context, and returns the findings.
+	Check(ctx *Context) ([]*Finding, error)
+}
+
+var (
+	ruleMu sync.RWMutex
+	rules  = make(map[RuleType]Rule)
+)
+
+// Register makes a mapper lint rule available by the provided type.
+// If Register is called twice with the same type or if rule is nil,
+// it panics.
+func Register(ruleType RuleType, r Rule) {
+	ruleMu.Lock()
+	defer ruleMu.Unlock()
+	if r == nil {
+		panic("lint: Register rule is nil")
+	}
+	if _, dup := rules[ruleType]; dup {
+		panic(fmt.Sprintf("lint: Register called twice for rule %v", ruleType))
+	}
+	rules[ruleType] = r
+}
+
+func getRule(ruleType RuleType) (Rule, bool) {
+	ruleMu.RLock()
+	defer ruleMu.RUnlock()
+	r, ok := rules[ruleType]
+	return r, ok
+}
+
+// celRuleNameRegexp matches the name of the user-defined rule, the comma and spaces are not allowed because the rules
+// are separated by them in the bb:ignore directive.
+var celRuleNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// getRuleIdentity returns the identity of the mapper lint rule, which is the rule type of the findings and the key of
+// the suppressions, baselines and overrides. It is the rule type for the built-in rules, and
+// "mybatis.cel-expression/<name>" for the user-defined rules, so the user-defined rules are distinguished by the
+// name in the payload.
+func getRuleIdentity(ruleType RuleType, params map[string]string) (RuleType, error) {
+	if ruleType != RuleCELExpression {
+		return ruleType, nil
+	}
+	name := params["name"]
+	if !celRuleNameRegexp.MatchString(name) {
+		return "", errors.Errorf("the name of rule %q is required and can only contain letters, digits, \"_\", \".\" and \"-\", but got %q", ruleType, name)
+	}
+	return RuleType(fmt.Sprintf("%s/%s", RuleCELExpression, name)), nil
+}
+
+// parseRuleIdentity returns the rule type and the name of the user-defined rule of the identity returned by
+// getRuleIdentity, ok is false if the identity is not of a mapper lint rule.
+func parseRuleIdentity(identity RuleType) (ruleType RuleType, name string, ok bool) {
+	if prefix, name, found := strings.Cut(string(identity), "/"); found {
+		if RuleType(prefix) != RuleCELExpression || !celRuleNameRegexp.MatchString(name) {
+			return "", "", false
+		}
+		return RuleCELExpression, name, true
+	}
+	if identity == RuleCELExpression {
+		return "", "", false
+	}
+	if _, ok := getRule(identity); !ok {
+		return "", "", false
+	}
+	return identity, "", true
+}
+
+// CheckContext is the context for checking the mybatis mapper.
+type CheckContext struct {
+	// Catalog is the catalog of the database which the mapper statements run against, it can be nil if the schema is unknown.
+	Catalog *catalog.Finder
+	// FingerprintOptions is the options of computing the statement fingerprint, which is the key of the baseline.
+	FingerprintOptions FingerprintOptions
+	// MaxIncludeDepth is the max depth of the nested <include> elements, 0 means ast.DefaultMaxIncludeDepth.
+	MaxIncludeDepth int
+	// MaxOutputSize is the max bytes of the SQL restored from a statement, 0 means ast.DefaultMaxOutputSize.
+	MaxOutputSize int
+	// MaxFileOutputSize is the max total bytes of the SQL restored from the statements in the mapper file, 0 means
+	// no limit. The statements after the limit is exceeded are not restored and reported by RuleExpansionLimit, so
+	// the restored SQL of a file is bounded by MaxFileOutputSize + MaxOutputSize.
+	MaxFileOutputSize int
+	// TypeAliasResolver resolves the type aliases in the parameterType and resultType attributes, it is usually
+	// created from the mybatis configuration xml. Only the built-in type aliases are resolved if it is nil.
+	TypeAliasResolver *configuration.TypeAliasResolver
+	// SQLMap is the sql fragments declared in the other mappers, key is the namespace.id of the sql element. It is
+	// used to resolve the <include> of the fragments across mappers, and can be nil.
+	SQLMap map[string]*ast.SQLNode
+}
+
+// Check checks the statements in the mybatis mapper AST with the mapper lint rules in the rule list, and returns the findings.
+// The rules which are not mapper lint rules are ignored, so the rule list of SQL review policy can be passed directly.
+func Check(root *ast.RootNode, ruleList []*storepb.SQLReviewRule, checkContext CheckContext) ([]*Finding, error) {
+	findings := getInvalidDirectiveFindings(root)
+	// restoredSize is the total bytes of the SQL restored from the statements in the file.
+	restoredSize := 0
+	typeAliasResolver := checkContext.TypeAliasResolver
+	if typeAliasResolver == nil {
+		typeAliasResolver = configuration.NewTypeAliasResolver(nil)
+	}
+	for _, child := range root.Children {
+		mapperNode, ok := child.(*ast.MapperNode)
+		if !ok {
+			continue
+		}
+		sqlMap := make(map[string]*ast.SQLNode)
+		for key, sqlNode := range checkContext.SQLMap {
+			sqlMap[key] = sqlNode
+		}
+		parameterMaps := make(map[string]*ast.ParameterMapNode)
+		for _, node := range mapperNode.Children {
+			switch n := node.(type) {
+			case *ast.SQLNode:
+				sqlMap[n.ID] = n
+				sqlMap[mapperNode.Namespace+"."+n.ID] = n
+			case *ast.ParameterMapNode:
+				parameterMaps[n.ID] = n
+				parameterMaps[mapperNode.Namespace+"."+n.ID] = n
+			}
+		}
+		// The metadata of all the statements are built before checking, so the rules can compare the statement with the
+		// others in the mapper.
+		var contexts []*Context
+		var mapperMetadata []*StatementMetadata
+		for _, node := range mapperNode.Children {
+			queryNode, ok := node.(*ast.QueryNode)
+			if !ok {
+				continue
+			}
+			ctx := &Context{
+				Namespace:     mapperNode.Namespace,
+				Mapper:        mapperNode,
+				Statement:     queryNode,
+				SQLMap:        sqlMap,
+				ParameterMaps: parameterMaps,
+				Catalog:       checkContext.Catalog,
+				overrides:     getConfigOverrides(root.Directives[queryNode]),
+
+				fingerprintOptions: checkContext.FingerprintOptions,
+				maxIncludeDepth:    checkContext.MaxIncludeDepth,
+				maxOutputSize:      checkContext.MaxOutputSize,
+				typeAliasResolver:  typeAliasResolver,
+			}
+			if checkContext.MaxFileOutputSize > 0 && restoredSize >= checkContext.MaxFileOutputSize {
+				ctx.restoreErr = &ast.ExpansionLimitError{Kind: ast.ExpansionLimitFileOutputSize, Limit: checkContext.MaxFileOutputSize}
+			}
+			metadata, err := newStatementMetadata(ctx)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to build metadata of statement %q in mapper %q", queryNode.ID, mapperNode.Namespace)
+			}
+			ctx.Metadata = metadata
+			restoredSize += len(metadata.SQL)
+			contexts = append(contexts, ctx)
+			mapperMetadata = append(mapperMetadata, metadata)
+		}
+		for _, ctx := range contexts {
+			ctx.MapperMetadata = mapperMetadata
+			statementFindings, err := checkStatement(ctx, ruleList)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to check statement %q in mapper %q", ctx.Statement.ID, mapperNode.Namespace)
+			}
+			if ctx.restoreErr != nil {
+				statementFindings = append([]*Finding{newRestoreFailureFinding(ctx)}, statementFindings...)
+			}
+			applySuppressions(root, ctx, statementFindings)
+			findings = append(findings, statementFindings...)
+		}
+	}
+	return findings, nil
+}
+
+// getInvalidDirectiveFindings returns the findings of the invalid directives in the mapper.
+func getInvalidDirectiveFindings(root *ast.RootNode) []*Finding {
+	var namespace string
+	for _, child := range root.Children {
+		if mapperNode, ok := child.(*ast.MapperNode); ok {
+			namespace = mapperNode.Namespace
+			break
+		}
+	}
+	var findings []*Finding
+	for _, invalidDirective := range root.InvalidDirectives {
+		content := fmt.Sprintf("the directive is ignored because %v", invalidDirective.Err)
+		findings = append(findings, &Finding{
+			Rule:        RuleInvalidDirective,
+			Level:       storepb.SQLReviewRuleLevel_WARNING,
+			Namespace:   namespace,
+			Line:        invalidDirective.Line,
+			Title:       string(RuleInvalidDirective),
+			Content:     content,
+			Fingerprint: invalidDirectiveFingerprint(namespace, content),
+		})
+	}
+	return findings
+}
+
+// newRestoreFailureFinding returns the finding of the statement which cannot be restored, the statement exceeding
+// the expansion limit is reported by RuleExpansionLimit.
+func newRestoreFailureFinding(ctx *Context) *Finding {
+	finding := &Finding{
+		Rule:        RuleRestoreFailure,
+		Level:       storepb.SQLReviewRuleLevel_WARNING,
+		Namespace:   ctx.Namespace,
+		StatementID: ctx.Statement.ID,
+		Line:        ctx.Statement.Line,
+		Fingerprint: getFindingFingerprint(ctx),
+	}
+	var limitErr *ast.ExpansionLimitError
+	if errors.As(ctx.restoreErr, &limitErr) {
+		finding.Rule = RuleExpansionLimit
+		finding.Level = storepb.SQLReviewRuleLevel_ERROR
+		finding.Content = fmt.Sprintf("failed to restore \"%s\" because the %s, the checks based on the restored SQL are skipped", ctx.Statement.ID, limitErr.Error())
+	} else {
+		finding.Content = fmt.Sprintf("failed to restore \"%s\": %v, the checks based on the restored SQL are skipped", ctx.Statement.ID, errors.Cause(ctx.restoreErr))
+	}
+	finding.Title = string(finding.Rule)
+	return finding
+}
+
+// getFindingFingerprint returns the fingerprint of the findings on the statement, the fallback fingerprint is used if
+// the statement cannot be restored.
+func getFindingFingerprint(ctx *Context) string {
+	if ctx.Metadata.Fingerprint != "" {
+		return ctx.Metadata.Fingerprint
+	}
+	return fallbackFingerprint(ctx.Namespace, ctx.Statement)
+}
+
+func checkStatement(ctx *Context, ruleList []*storepb.SQLReviewRule) ([]*Finding, error) {
+	var findings []*Finding
+	for _, rule := range ruleList {
+		if rule.Level == storepb.SQLReviewRuleLevel_DISABLED {
+			continue
+		}
+		r, ok := getRule(RuleType(rule.Type))
+		if !ok {
+			continue
+		}
+		params, err := unmarshalRuleParams(rule.Payload)
+		if err != nil {
+			return nil, err
+		}
+		identity, err := getRuleIdentity(RuleType(rule.Type), params)
+		if err != nil {
+			return nil, err
+		}
+		ctx.Rule = rule
+		ctx.params = params
+		ctx.usedOverrides = make(map[string]string)
+		ruleFindings, err := checkRule(r, ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, finding := range ruleFindings {
+			if len(ctx.usedOverrides) > 0 {
+				finding.Overrides = ctx.usedOverrides
+			}
+			finding.Rule = identity
+			finding.Level = rule.Level
+			finding.Namespace = ctx.Namespace
+			finding.StatementID = ctx.Statement.ID
+			finding.Fingerprint = getFindingFingerprint(ctx)
+			if finding.Line == 0 {
+				finding.Line = ctx.Statement.Line
+			}
+			if finding.Title == "" {
+				finding.Title = string(identity)
+			}
+		}
+		findings = append(findings, ruleFindings...)
+	}
+	ctx.Rule, ctx.params, ctx.usedOverrides = nil, nil, nil
+	return findings, nil
+}
+
+// getConfigOverrides returns the rule parameters defined in the bb:config directives, the latter directive wins.
+func getConfigOverrides(directives []*ast.Directive) map[string]string {
+	overrides := make(map[string]string)
+	for _, directive := range directives {
+		if directive.Kind != ast.DirectiveKindConfig {
+			continue
+		}
+		for name, value := range directive.Args {
+			overrides[name] = value
+		}
+	}
+	return overrides
+}
+
+func checkRule(r Rule, ctx *Context) (findings []*Finding, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			err = errors.Errorf("panic in mapper lint rule check, in type: %v, because: %v", ctx.Rule.Type, panicErr)
+		}
+	}()
+	return r.Check(ctx)
+}
+
+// applySuppressions marks the findings suppressed if the bb:ignore directive of the rule is placed above the mapper,
+// the statement, or any element containing the node of the finding.
+func applySuppressions(root *ast.RootNode, ctx *Context, findings []*Finding) {
+	if len(findings) == 0 || len(root.Directives) == 0 {
+		return
+	}
+	// Record the path of each node, the path of the first visit wins if the sql fragment is included multiple times.
+	paths := make(map[ast.Node][]ast.Node)
+	if err := ctx.Walk(func(node ast.Node, path []ast.Node, _ map[string]string) error {
+		if _, ok := paths[node]; !ok {
+			paths[node] = path
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+	for _, finding := range findings {
+		scopes := []ast.Node{ctx.Mapper}
+		if finding.Node == nil {
+			scopes = append(scopes, ctx.Statement)
+		} else {
+			scopes = append(scopes, paths[finding.Node]...)
+			scopes = append(scopes, finding.Node)
+		}
+		for _, scope := range scopes {
+			if directive := findIgnoreDirective(root.Directives[scope], finding.Rule); directive != nil {
+				finding.Suppressed = true
+				finding.SuppressReason = directive.Args["reason"]
+				break
+			}
+		}
+	}
+}
+
+func findIgnoreDirective(directives []*ast.Directive, ruleType RuleType) *ast.Directive {
+	for _, directive := range directives {
+		if directive.Kind != ast.DirectiveKindIgnore {
+			continue
+		}
+		for _, rule := range strings.Split(directive.Args["rule"], ",") {
+			if RuleType(strings.TrimSpace(rule)) == ruleType {
+				return directive
+			}
+		}
+	}
+	return nil
+}
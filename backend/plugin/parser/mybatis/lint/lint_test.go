@@ -0,0 +1,734 @@
+package lint
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytebase/bytebase/backend/plugin/advisor/catalog"
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/configuration"
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper"
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper/ast"
+	storepb "github.com/bytebase/bytebase/proto/generated-go/store"
+)
+
+// ruleTestInListLimit is the rule type of the fake rule which reports the value of parameter "max-in-list".
+const ruleTestInListLimit RuleType = "mybatis.test.in-list-limit"
+
+// ruleTestMetadata is the rule type of the fake rule which reports the statement metadata and the catalog it receives.
+const ruleTestMetadata RuleType = "mybatis.test.metadata"
+
+func init() {
+	Register(ruleTestInListLimit, &inListLimitTestRule{})
+	Register(ruleTestMetadata, testMetadataRule)
+}
+
+var testMetadataRule = &metadataTestRule{}
+
+type metadataTestRule struct {
+	metadata   *StatementMetadata
+	hasCatalog bool
+}
+
+func (r *metadataTestRule) Check(ctx *Context) ([]*Finding, error) {
+	r.metadata = ctx.Metadata
+	r.hasCatalog = ctx.Catalog != nil
+	return []*Finding{
+		{
+			Content: "metadata received",
+		},
+	}, nil
+}
+
+type inListLimitTestRule struct {
+}
+
+func (*inListLimitTestRule) Check(ctx *Context) ([]*Finding, error) {
+	value, _ := ctx.Param("max-in-list")
+	return []*Finding{
+		{
+			Content: fmt.Sprintf("max-in-list is %s", value),
+		},
+	}, nil
+}
+
+// findingResult is the comparable part of the finding.
+type findingResult struct {
+	Rule           RuleType
+	StatementID    string
+	Line           int
+	Suppressed     bool
+	SuppressReason string
+}
+
+func runCheck(t *testing.T, xml string, ruleList []*storepb.SQLReviewRule) []*Finding {
+	return runCheckWithContext(t, xml, ruleList, CheckContext{})
+}
+
+func runCheckWithContext(t *testing.T, xml string, ruleList []*storepb.SQLReviewRule, checkContext CheckContext) []*Finding {
+	parser := mapper.NewParser(xml)
+	root, err := parser.Parse()
+	require.NoError(t, err)
+	findings, err := Check(root, ruleList, checkContext)
+	require.NoError(t, err)
+	return findings
+}
+
+func toFindingResults(findings []*Finding) []findingResult {
+	var results []findingResult
+	for _, finding := range findings {
+		results = append(results, findingResult{
+			Rule:           finding.Rule,
+			StatementID:    finding.StatementID,
+			Line:           finding.Line,
+			Suppressed:     finding.Suppressed,
+			SuppressReason: finding.SuppressReason,
+		})
+	}
+	return results
+}
+
+func TestNoDollarSubstitutionWithSuppression(t *testing.T) {
+	ruleList := []*storepb.SQLReviewRule{
+		{
+			Type:  string(RuleNoDollarSubstitution),
+			Level: storepb.SQLReviewRuleLevel_ERROR,
+		},
+	}
+	testCases := []struct {
+		xml  string
+		want []findingResult
+	}{
+		{
+			xml: `<mapper namespace="com.bytebase.test">
+	<select id="selectByOrder">
+		SELECT * FROM t ORDER BY ${orderBy}
+	</select>
+</mapper>`,
+			want: []findingResult{
+				{Rule: RuleNoDollarSubstitution, StatementID: "selectByOrder", Line: 2},
+			},
+		},
+		{
+			xml: `<mapper namespace="com.bytebase.test">
+	<!-- bb:ignore rule=mybatis.no-dollar-substitution reason="ORDER BY whitelist" -->
+	<select id="selectByOrder">
+		SELECT * FROM t ORDER BY ${orderBy}
+	</select>
+	<select id="selectByName">
+		SELECT * FROM t WHERE name = ${name}
+	</select>
+</mapper>`,
+			want: []findingResult{
+				{Rule: RuleNoDollarSubstitution, StatementID: "selectByOrder", Line: 3, Suppressed: true, SuppressReason: "ORDER BY whitelist"},
+				{Rule: RuleNoDollarSubstitution, StatementID: "selectByName", Line: 6},
+			},
+		},
+		{
+			xml: `<mapper namespace="com.bytebase.test">
+	<select id="selectByFilter">
+		SELECT * FROM t WHERE name = ${name}
+		<!-- bb:ignore rule=mybatis.no-dollar-substitution,mybatis.other reason="validated column" -->
+		<if test="orderBy != null">
+			ORDER BY ${orderBy}
+		</if>
+	</select>
+</mapper>`,
+			want: []findingResult{
+				{Rule: RuleNoDollarSubstitution, StatementID: "selectByFilter", Line: 2},
+				{Rule: RuleNoDollarSubstitution, StatementID: "selectByFilter", Line: 2, Suppressed: true, SuppressReason: "validated column"},
+			},
+		},
+		{
+			// The ignore directive of other rules does not suppress the finding.
+			xml: `<mapper namespace="com.bytebase.test">
+	<!-- bb:ignore rule=mybatis.other -->
+	<select id="selectByOrder">
+		SELECT * FROM t ORDER BY ${orderBy}
+	</select>
+</mapper>`,
+			want: []findingResult{
+				{Rule: RuleNoDollarSubstitution, StatementID: "selectByOrder", Line: 3},
+			},
+		},
+		{
+			// The directive is dropped and reported if it is not followed by an element directly.
+			xml: `<mapper namespace="com.bytebase.test">
+	<select id="selectByOrder">
+		<!-- bb:ignore rule=mybatis.no-dollar-substitution -->
+		SELECT * FROM t ORDER BY ${orderBy}
+	</select>
+</mapper>`,
+			want: []findingResult{
+				{Rule: RuleInvalidDirective, Line: 3},
+				{Rule: RuleNoDollarSubstitution, StatementID: "selectByOrder", Line: 2},
+			},
+		},
+		{
+			// The ${} replaced by the include property is allowed, and the directive above the fragment applies to the included content.
+			xml: `<mapper namespace="com.bytebase.test">
+	<sql id="table">${prefix}_table</sql>
+	<!-- bb:ignore rule=mybatis.no-dollar-substitution reason="trusted fragment" -->
+	<sql id="order">ORDER BY ${orderBy}</sql>
+	<select id="select">
+		SELECT * FROM
+		<include refid="table">
+			<property name="prefix" value="some"/>
+		</include>
+		<include refid="order"/>
+	</select>
+</mapper>`,
+			want: []findingResult{
+				{Rule: RuleNoDollarSubstitution, StatementID: "select", Line: 5, Suppressed: true, SuppressReason: "trusted fragment"},
+			},
+		},
+		{
+			// The body of the non-XML language driver is scanned as the raw text, the #{} and @{} parameters are allowed.
+			xml: `<mapper namespace="com.bytebase.test">
+	<select id="selectByVelocity" lang="velocity">
+		SELECT * FROM t WHERE id = @{id} ORDER BY ${orderBy}
+	</select>
+	<select id="selectByFreeMarker" lang="freemarker"><![CDATA[SELECT * FROM ${table} WHERE id = <@p name="id"/>]]></select>
+	<select id="selectByCustom" lang="com.acme.CustomDriver">SELECT * FROM t WHERE id = #{id} AND $!{ filter }</select>
+</mapper>`,
+			want: []findingResult{
+				{Rule: RuleNoDollarSubstitution, StatementID: "selectByVelocity", Line: 2},
+				{Rule: RuleNoDollarSubstitution, StatementID: "selectByFreeMarker", Line: 5},
+				{Rule: RuleNoDollarSubstitution, StatementID: "selectByCustom", Line: 6},
+			},
+		},
+		{
+			// The malformed directive does not fail the check, it is reported and ignored.
+			xml: `<mapper namespace="com.bytebase.test">
+	<!-- bb:ignore reason="missing rule" -->
+	<select id="selectByOrder">
+		SELECT * FROM t ORDER BY ${orderBy}
+	</select>
+</mapper>`,
+			want: []findingResult{
+				{Rule: RuleInvalidDirective, Line: 2},
+				{Rule: RuleNoDollarSubstitution, StatementID: "selectByOrder", Line: 3},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		findings := runCheck(t, tc.xml, ruleList)
+		require.Equal(t, tc.want, toFindingResults(findings), tc.xml)
+	}
+}
+
+func TestCheckSkipsDisabledRule(t *testing.T) {
+	xml := `<mapper namespace="com.bytebase.test">
+	<select id="selectByOrder">
+		SELECT * FROM t ORDER BY ${orderBy}
+	</select>
+</mapper>`
+	findings := runCheck(t, xml, []*storepb.SQLReviewRule{
+		{
+			Type:  string(RuleNoDollarSubstitution),
+			Level: storepb.SQLReviewRuleLevel_DISABLED,
+		},
+	})
+	require.Empty(t, findings)
+}
+
+func TestConfigDirective(t *testing.T) {
+	xml := `<mapper namespace="com.bytebase.test">
+	<!-- bb:config max-in-list=500 unused=1 -->
+	<select id="selectWithOverride">
+		SELECT * FROM t
+	</select>
+	<!-- bb:config max-in-list=100 -->
+	<sql id="fragment">t</sql>
+	<select id="selectWithoutOverride">
+		SELECT * FROM <include refid="fragment"/>
+	</select>
+</mapper>`
+	findings := runCheck(t, xml, []*storepb.SQLReviewRule{
+		{
+			Type:    string(ruleTestInListLimit),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"max-in-list": 1000}`,
+		},
+	})
+	require.Len(t, findings, 3)
+
+	// The bb:config directive only applies to the following statement, the one above the sql fragment is reported.
+	require.Equal(t, RuleInvalidDirective, findings[0].Rule)
+	require.Equal(t, 6, findings[0].Line)
+	require.Equal(t, `the directive is ignored because directive "config" is not followed by a statement, but <sql>`, findings[0].Content)
+
+	require.Equal(t, "selectWithOverride", findings[1].StatementID)
+	require.Equal(t, "max-in-list is 500", findings[1].Content)
+	require.Equal(t, map[string]string{"max-in-list": "500"}, findings[1].Overrides)
+
+	require.Equal(t, "selectWithoutOverride", findings[2].StatementID)
+	require.Equal(t, "max-in-list is 1000", findings[2].Content)
+	require.Nil(t, findings[2].Overrides)
+}
+
+func TestCustomRuleContext(t *testing.T) {
+	xml := `<mapper namespace="com.bytebase.test">
+	<sql id="columns">id, name</sql>
+	<sql id="table">${prefix}_user</sql>
+	<select id="selectByFilter" timeout="30" fetchSize="${defaultFetchSize}" useCache="false">
+		SELECT <include refid="columns"/> FROM
+		<include refid="table">
+			<property name="prefix" value="t"/>
+		</include>
+		<where>
+			<if test="name != null">AND name = #{name}</if>
+			<if test="id != null">AND id = #{id,jdbcType=INTEGER}</if>
+		</where>
+		ORDER BY ${orderBy}
+	</select>
+</mapper>`
+	findings := runCheckWithContext(t, xml, []*storepb.SQLReviewRule{
+		{
+			Type:  string(ruleTestMetadata),
+			Level: storepb.SQLReviewRuleLevel_ERROR,
+		},
+	}, CheckContext{
+		Catalog: catalog.NewEmptyFinder(&catalog.FinderContext{EngineType: storepb.Engine_MYSQL}),
+	})
+	require.Len(t, findings, 1)
+	want := &StatementMetadata{
+		Namespace:      "com.bytebase.test",
+		ID:             "selectByFilter",
+		Kind:           "select",
+		Line:           4,
+		ParameterNames: []string{"id", "name"},
+		VariableNames:  []string{"orderBy"},
+		Fragments:      []string{"columns", "table"},
+		SQL:            "SELECT id, name FROM t_user WHERE name = ?  AND id = ? ORDER BY ?",
+		Tables:         []string{"t_user"},
+		NormalizedSQL:  "SELECT id , name FROM t_user WHERE name = ? AND id = ? ORDER BY ?",
+		Fingerprint:    findings[0].Fingerprint,
+		Timeout:        30,
+		StatementType:  "PREPARED",
+		LanguageDriver: "xml",
+	}
+	require.NotEmpty(t, findings[0].Fingerprint)
+	require.Equal(t, want, testMetadataRule.metadata)
+	require.True(t, testMetadataRule.hasCatalog)
+	require.Equal(t, ruleTestMetadata, findings[0].Rule)
+	require.Equal(t, storepb.SQLReviewRuleLevel_ERROR, findings[0].Level)
+}
+
+func TestCELExpressionRule(t *testing.T) {
+	xml := `<mapper namespace="com.bytebase.test">
+	<select id="selectOrders">
+		SELECT * FROM orders o JOIN users u ON o.user_id = u.id WHERE u.name = #{name}
+	</select>
+	<select id="selectOrdersWithLimit">
+		SELECT * FROM orders LIMIT 10
+	</select>
+	<select id="selectUsers">
+		SELECT * FROM users ORDER BY ${orderBy}
+	</select>
+</mapper>`
+	findings := runCheck(t, xml, []*storepb.SQLReviewRule{
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"name": "orders-require-limit", "expression": "statement.kind == \"select\" && \"orders\" in statement.tables && !statement.has_limit", "title": "orders.require-limit", "message": "SELECT on orders must have LIMIT"}`,
+		},
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_ERROR,
+			Payload: `{"name": "no-dollar-without-parameter", "expression": "statement.has_dollar_substitution && statement.parameter_names.size() == 0"}`,
+		},
+	})
+	require.Len(t, findings, 2)
+	require.Equal(t, "selectOrders", findings[0].StatementID)
+	require.Equal(t, "orders.require-limit", findings[0].Title)
+	require.Equal(t, "SELECT on orders must have LIMIT", findings[0].Content)
+	require.Equal(t, storepb.SQLReviewRuleLevel_WARNING, findings[0].Level)
+	require.Equal(t, RuleCELExpression+"/orders-require-limit", findings[0].Rule)
+	require.Equal(t, "selectUsers", findings[1].StatementID)
+	require.Equal(t, RuleCELExpression+"/no-dollar-without-parameter", findings[1].Rule)
+	require.Equal(t, string(RuleCELExpression)+"/no-dollar-without-parameter", findings[1].Title)
+	require.Equal(t, storepb.SQLReviewRuleLevel_ERROR, findings[1].Level)
+
+	// The user-defined rules are suppressed separately by the name.
+	findings = runCheck(t, `<mapper namespace="com.bytebase.test">
+	<!-- bb:ignore rule=mybatis.cel-expression/a -->
+	<select id="selectAll">SELECT * FROM orders</select>
+</mapper>`, []*storepb.SQLReviewRule{
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"name": "a", "expression": "true"}`,
+		},
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"name": "b", "expression": "true"}`,
+		},
+	})
+	require.Len(t, findings, 2)
+	require.True(t, findings[0].Suppressed)
+	require.False(t, findings[1].Suppressed)
+
+	// The name of the user-defined rule is required.
+	root, err := mapper.NewParser(xml).Parse()
+	require.NoError(t, err)
+	_, err = Check(root, []*storepb.SQLReviewRule{
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"expression": "true"}`,
+		},
+	}, CheckContext{})
+	require.ErrorContains(t, err, `the name of rule "mybatis.cel-expression" is required`)
+
+	// The execution-tuning attributes.
+	findings = runCheck(t, `<mapper namespace="com.bytebase.test">
+	<select id="selectWithTimeout" timeout="10" fetchSize="1000">SELECT * FROM orders</select>
+	<select id="selectWithoutTimeout" flushCache="true">SELECT * FROM orders</select>
+	<update id="updateWithoutTimeout" statementType="CALLABLE">{call update_orders()}</update>
+</mapper>`, []*storepb.SQLReviewRule{
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"name": "require-timeout", "expression": "statement.timeout == 0 && (statement.flush_cache || statement.statement_type == \"CALLABLE\")"}`,
+		},
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_ERROR,
+			Payload: `{"name": "large-fetch-size", "expression": "statement.kind == \"select\" && statement.use_cache && statement.fetch_size > 500"}`,
+		},
+	})
+	require.Len(t, findings, 3)
+	require.Equal(t, "selectWithTimeout", findings[0].StatementID)
+	require.Equal(t, storepb.SQLReviewRuleLevel_ERROR, findings[0].Level)
+	require.Equal(t, "selectWithoutTimeout", findings[1].StatementID)
+	require.Equal(t, "updateWithoutTimeout", findings[2].StatementID)
+
+	// The statement of unknown language driver is flagged as raw text instead of failing the file.
+	findings = runCheck(t, `<mapper namespace="com.bytebase.test">
+	<select id="selectByCustom" lang="com.acme.CustomDriver">SELECT * FROM t WHERE <filter column="name"/></select>
+	<select id="selectByVelocity" lang="velocity">SELECT * FROM t WHERE id = @{id}</select>
+</mapper>`, []*storepb.SQLReviewRule{
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"name": "raw-text", "expression": "statement.raw_text || statement.language_driver != \"velocity\""}`,
+		},
+	})
+	require.Len(t, findings, 1)
+	require.Equal(t, "selectByCustom", findings[0].StatementID)
+
+	// The ${} in the body of the non-XML language driver is in the variable names.
+	findings = runCheck(t, `<mapper namespace="com.bytebase.test">
+	<select id="selectByFreeMarker" lang="freemarker">SELECT * FROM t ORDER BY ${col}</select>
+	<select id="selectByVelocity" lang="velocity">SELECT * FROM t WHERE id = @{id}</select>
+</mapper>`, []*storepb.SQLReviewRule{
+		{
+			Type:    string(RuleCELExpression),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"name": "script-dollar", "expression": "statement.has_dollar_substitution && \"col\" in statement.variable_names"}`,
+		},
+	})
+	require.Len(t, findings, 1)
+	require.Equal(t, "selectByFreeMarker", findings[0].StatementID)
+
+	require.Error(t, ValidateStatementExpression(`statement.unknown == "select"`))
+	require.Error(t, ValidateStatementExpression(`statement.kind`))
+	require.NoError(t, ValidateStatementExpression(`statement.kind == "delete" && !statement.has_limit`))
+}
+
+func TestExtractTables(t *testing.T) {
+	testCases := []struct {
+		sql      string
+		tables   []string
+		hasLimit bool
+	}{
+		{
+			sql:    "SELECT a.id FROM `db`.`a` AS a, b bb LEFT JOIN c ON b.id = c.id WHERE a.name = 'FROM x'",
+			tables: []string{"b", "c", "db.a"},
+		},
+		{
+			sql:      "SELECT * FROM (SELECT * FROM public.t1) s JOIN \"T2\" USING (id) FETCH FIRST 10 ROWS ONLY",
+			tables:   []string{"T2", "public.t1"},
+			hasLimit: true,
+		},
+		{
+			sql:    "INSERT INTO t (a, b) SELECT a, b FROM s",
+			tables: []string{"s", "t"},
+		},
+		{
+			sql:      "UPDATE t SET a = ? WHERE id IN (SELECT id FROM s) LIMIT 1",
+			tables:   []string{"s", "t"},
+			hasLimit: true,
+		},
+		{
+			sql:    "DELETE FROM t WHERE id = ?",
+			tables: []string{"t"},
+		},
+		{
+			sql:    "SELECT EXTRACT(YEAR FROM created_at), TRIM(LEADING '0' FROM (code)) FROM t WHERE SUBSTRING(name FROM 2) = ?",
+			tables: []string{"t"},
+		},
+	}
+	for _, tc := range testCases {
+		tokens := scanSQL(tc.sql)
+		require.Equal(t, tc.tables, extractTables(tokens), tc.sql)
+		require.Equal(t, tc.hasLimit, hasLimit(tokens), tc.sql)
+	}
+}
+
+func TestMetadataTablesByEngineParser(t *testing.T) {
+	ruleList := []*storepb.SQLReviewRule{
+		{
+			Type:  string(ruleTestMetadata),
+			Level: storepb.SQLReviewRuleLevel_WARNING,
+		},
+	}
+	getTables := func(engine storepb.Engine, sql string) []string {
+		runCheckWithContext(t, `<mapper namespace="com.bytebase.test"><select id="s">`+sql+`</select></mapper>`, ruleList, CheckContext{
+			FingerprintOptions: FingerprintOptions{Engine: engine},
+		})
+		return testMetadataRule.metadata.Tables
+	}
+	sql := `SELECT o.id, EXTRACT(YEAR FROM o.created_at) FROM orders o JOIN users u ON o.user_id = u.id
+		WHERE o.id IN (SELECT order_id FROM items WHERE price &gt; #{price})`
+	require.Equal(t, []string{"items", "orders", "users"}, getTables(storepb.Engine_MYSQL, sql))
+	require.Equal(t, []string{"items", "orders", "users"}, getTables(storepb.Engine_ENGINE_UNSPECIFIED, sql))
+	// The SQL which cannot be parsed by the engine parser falls back to the lexical heuristic.
+	require.Equal(t, []string{"orders"}, getTables(storepb.Engine_MYSQL, "SELECT * FROM orders WHERE"))
+}
+
+func TestRequireTimeoutRule(t *testing.T) {
+	xml := `<mapper namespace="com.bytebase.test">
+	<select id="selectWithoutLimit">SELECT * FROM orders</select>
+	<select id="selectWithLimit">SELECT * FROM orders LIMIT 10</select>
+	<select id="selectWithTimeout" timeout="${query.timeout}">SELECT * FROM orders</select>
+	<select id="selectJoin" fetchSize="100">
+		SELECT * FROM orders o JOIN users u ON o.user_id = u.id JOIN items i ON o.item_id = i.id LIMIT 10
+	</select>
+	<delete id="deleteByIDs">
+		DELETE FROM orders WHERE id IN
+		<foreach collection="ids" item="id" open="(" separator="," close=")">#{id}</foreach>
+	</delete>
+	<!-- bb:config max-referenced-tables=1 -->
+	<update id="updateByIDs">
+		UPDATE orders o JOIN users u ON o.user_id = u.id SET o.status = #{status}
+		<where><foreach collection="ids" item="id" open="o.id IN (" separator="," close=")">#{id}</foreach></where>
+	</update>
+</mapper>`
+	findings := runCheck(t, xml, []*storepb.SQLReviewRule{
+		{
+			Type:    string(RuleRequireTimeout),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"max-referenced-tables": 2, "check-no-limit": true, "require-fetch-size": true}`,
+		},
+	})
+	var contents []string
+	for _, finding := range findings {
+		contents = append(contents, finding.Content)
+	}
+	require.Equal(t, []string{
+		`"selectWithoutLimit" is expensive because it has no LIMIT, set the timeout and fetchSize attributes to bound it`,
+		`"selectWithTimeout" is expensive because it has no LIMIT, set the fetchSize attribute to bound it`,
+		`"selectJoin" is expensive because it references 3 tables which is more than 2, set the timeout attribute to bound it`,
+		`"deleteByIDs" is expensive because it builds the IN list by <foreach>, set the timeout attribute to bound it`,
+		`"updateByIDs" is expensive because it references 2 tables which is more than 1 and it builds the IN list by <foreach>, set the timeout attribute to bound it`,
+	}, contents)
+
+	root, err := mapper.NewParser(xml).Parse()
+	require.NoError(t, err)
+	_, err = Check(root, []*storepb.SQLReviewRule{
+		{
+			Type:    string(RuleRequireTimeout),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"max-referenced-tables": "many"}`,
+		},
+	}, CheckContext{})
+	require.ErrorContains(t, err, `invalid integer "many" of parameter "max-referenced-tables"`)
+
+	// The SELECT without LIMIT is not expensive by default.
+	findings = runCheck(t, `<mapper namespace="com.bytebase.test">
+	<select id="selectByName">SELECT * FROM orders WHERE name = #{name}</select>
+</mapper>`, []*storepb.SQLReviewRule{
+		{
+			Type:  string(RuleRequireTimeout),
+			Level: storepb.SQLReviewRuleLevel_WARNING,
+		},
+	})
+	require.Empty(t, findings)
+}
+
+func TestDuplicateStatementRule(t *testing.T) {
+	xml := `<mapper namespace="com.bytebase.test">
+	<select id="selectByID">SELECT * FROM orders WHERE id = #{id} AND deleted = 0</select>
+	<select id="selectByKey">
+		select *
+		from orders -- copied from selectByID
+		where id = #{key} and deleted = 1
+	</select>
+	<select id="selectDeleted">SELECT * FROM orders WHERE id = #{id} AND deleted IS NULL</select>
+	<select id="selectOrdersByID">SELECT * FROM Orders WHERE id = #{id} AND deleted = 0</select>
+	<select id="selectTemplate" lang="com.acme.CustomDriver">SELECT * FROM orders WHERE id = #{id} AND deleted = 0</select>
+	<select id="selectTemplateCopy" lang="com.acme.CustomDriver">SELECT * FROM orders WHERE id = #{id} AND deleted = 0</select>
+</mapper>`
+	findings := runCheck(t, xml, []*storepb.SQLReviewRule{
+		{
+			Type:  string(RuleDuplicateStatement),
+			Level: storepb.SQLReviewRuleLevel_WARNING,
+		},
+	})
+	var contents []string
+	for _, finding := range findings {
+		contents = append(contents, finding.Content)
+	}
+	// The identifiers are case-sensitive and the raw text of the unknown language drivers is not compared.
+	require.Equal(t, []string{
+		`"selectByKey" duplicates "selectByID" at line 2, the normalized SQL of them is the same`,
+	}, contents)
+}
+
+func TestExpansionLimit(t *testing.T) {
+	xml := `<mapper namespace="com.bytebase.test">
+	<sql id="a"><include refid="b"/></sql>
+	<sql id="b"><include refid="a"/></sql>
+	<select id="selectCircular">SELECT * FROM t WHERE <include refid="a"/></select>
+	<select id="selectByIDs">
+		SELECT * FROM t WHERE id IN <foreach collection="ids" item="id" open="(" separator="," close=")">#{id}</foreach>
+	</select>
+	<!-- bb:ignore rule=mybatis.expansion-limit reason="generated" -->
+	<select id="selectIgnored"><include refid="a"/></select>
+</mapper>`
+	ruleList := []*storepb.SQLReviewRule{
+		{
+			Type:  string(ruleTestMetadata),
+			Level: storepb.SQLReviewRuleLevel_WARNING,
+		},
+	}
+	// The rules are still checked for the statements exceeding the limit.
+	findings := runCheck(t, xml, ruleList)
+	require.Equal(t, []findingResult{
+		{Rule: RuleExpansionLimit, StatementID: "selectCircular", Line: 4},
+		{Rule: ruleTestMetadata, StatementID: "selectCircular", Line: 4},
+		{Rule: ruleTestMetadata, StatementID: "selectByIDs", Line: 5},
+		{Rule: RuleExpansionLimit, StatementID: "selectIgnored", Line: 9, Suppressed: true, SuppressReason: "generated"},
+		{Rule: ruleTestMetadata, StatementID: "selectIgnored", Line: 9},
+	}, toFindingResults(findings))
+	require.Equal(t, storepb.SQLReviewRuleLevel_ERROR, findings[0].Level)
+	require.Equal(t, `failed to restore "selectCircular" because the include "a" exceeds the max include depth 32, the include chain may be circular, the checks based on the restored SQL are skipped`, findings[0].Content)
+
+	findings = runCheckWithContext(t, xml, ruleList, CheckContext{MaxOutputSize: 32})
+	require.Equal(t, RuleExpansionLimit, findings[2].Rule)
+	require.Equal(t, `failed to restore "selectByIDs" because the restored SQL exceeds the max output size 32 bytes, the checks based on the restored SQL are skipped`, findings[2].Content)
+}
+
+func TestRestoreFailure(t *testing.T) {
+	xml := `<mapper namespace="com.bytebase.test">
+	<select id="selectOther">SELECT <include refid="com.bytebase.other.columns"/> FROM t ORDER BY ${orderBy}</select>
+	<select id="selectByName">SELECT * FROM t WHERE name = ${name}</select>
+</mapper>`
+	ruleList := []*storepb.SQLReviewRule{
+		{
+			Type:  string(RuleNoDollarSubstitution),
+			Level: storepb.SQLReviewRuleLevel_ERROR,
+		},
+		{
+			Type:    string(RuleRequireTimeout),
+			Level:   storepb.SQLReviewRuleLevel_WARNING,
+			Payload: `{"check-no-limit": true}`,
+		},
+	}
+	// The statement including the unknown fragment does not stop checking the other statements.
+	findings := runCheck(t, xml, ruleList)
+	require.Equal(t, []findingResult{
+		{Rule: RuleRestoreFailure, StatementID: "selectOther", Line: 2},
+		{Rule: RuleNoDollarSubstitution, StatementID: "selectOther", Line: 2},
+		{Rule: RuleNoDollarSubstitution, StatementID: "selectByName", Line: 3},
+		{Rule: RuleRequireTimeout, StatementID: "selectByName", Line: 3},
+	}, toFindingResults(findings))
+	require.Equal(t, `failed to restore "selectOther": refID com.bytebase.other.columns not found, the checks based on the restored SQL are skipped`, findings[0].Content)
+
+	// The fragment of the other mapper is resolved by the SQL map in the check context.
+	other, err := mapper.NewParser(`<mapper namespace="com.bytebase.other">
+	<sql id="columns">id, ${column}</sql>
+</mapper>`).Parse()
+	require.NoError(t, err)
+	sqlMap := make(map[string]*ast.SQLNode)
+	for _, node := range other.Children[0].(*ast.MapperNode).Children {
+		if sqlNode, ok := node.(*ast.SQLNode); ok {
+			sqlMap["com.bytebase.other."+sqlNode.ID] = sqlNode
+		}
+	}
+	findings = runCheckWithContext(t, xml, ruleList, CheckContext{SQLMap: sqlMap})
+	require.Equal(t, []findingResult{
+		{Rule: RuleNoDollarSubstitution, StatementID: "selectOther", Line: 2},
+		{Rule: RuleNoDollarSubstitution, StatementID: "selectOther", Line: 2},
+		{Rule: RuleRequireTimeout, StatementID: "selectOther", Line: 2},
+		{Rule: RuleNoDollarSubstitution, StatementID: "selectByName", Line: 3},
+		{Rule: RuleRequireTimeout, StatementID: "selectByName", Line: 3},
+	}, toFindingResults(findings))
+}
+
+func TestParameterMapMetadata(t *testing.T) {
+	xml := `<mapper namespace="com.bytebase.test">
+	<parameterMap id="orderParams" type="com.bytebase.Order">
+		<parameter property="status" jdbcType="VARCHAR"/>
+		<parameter property="id" javaType="int" jdbcType="INTEGER" mode="in"/>
+	</parameterMap>
+	<update id="updateStatus" parameterMap="com.bytebase.test.orderParams">
+		UPDATE orders SET status = ? WHERE id = ?
+	</update>
+</mapper>`
+	ruleList := []*storepb.SQLReviewRule{
+		{
+			Type:  string(ruleTestMetadata),
+			Level: storepb.SQLReviewRuleLevel_WARNING,
+		},
+	}
+	runCheck(t, xml, ruleList)
+	metadata := testMetadataRule.metadata
+	require.Equal(t, "com.bytebase.test.orderParams", metadata.ParameterMap)
+	require.Equal(t, []*ParameterMapping{
+		{Property: "status", JdbcType: "VARCHAR", Mode: "IN"},
+		{Property: "id", JavaType: "java.lang.Integer", JdbcType: "INTEGER", Mode: "IN"},
+	}, metadata.ParameterMappings)
+	require.Equal(t, "com.bytebase.Order", metadata.ParameterType)
+	require.Equal(t, []string{"id", "status"}, metadata.ParameterNames)
+	require.Equal(t, "UPDATE orders SET status = ? WHERE id = ?", metadata.SQL)
+
+	// The unresolved parameter map is kept as the reference only.
+	runCheck(t, `<mapper namespace="com.bytebase.test">
+	<update id="updateStatus" parameterMap="missingParams">UPDATE orders SET status = ?</update>
+</mapper>`, ruleList)
+	require.Equal(t, "missingParams", testMetadataRule.metadata.ParameterMap)
+	require.Nil(t, testMetadataRule.metadata.ParameterMappings)
+	require.Empty(t, testMetadataRule.metadata.ParameterNames)
+}
+
+func TestTypeAliasMetadata(t *testing.T) {
+	xml := `<mapper namespace="com.bytebase.test">
+	<select id="selectByID" parameterType="int" resultType="Order">SELECT * FROM orders WHERE id = #{id}</select>
+</mapper>`
+	ruleList := []*storepb.SQLReviewRule{
+		{
+			Type:  string(ruleTestMetadata),
+			Level: storepb.SQLReviewRuleLevel_WARNING,
+		},
+	}
+	// Only the built-in type aliases are resolved without the configuration.
+	runCheck(t, xml, ruleList)
+	require.Equal(t, "java.lang.Integer", testMetadataRule.metadata.ParameterType)
+	require.Equal(t, "Order", testMetadataRule.metadata.ResultType)
+
+	conf, err := configuration.ParseConfiguration(`<configuration>
+	<typeAliases>
+		<typeAlias alias="Order" type="com.bytebase.domain.Order"/>
+	</typeAliases>
+</configuration>`)
+	require.NoError(t, err)
+	runCheckWithContext(t, xml, ruleList, CheckContext{TypeAliasResolver: configuration.NewTypeAliasResolver(conf)})
+	require.Equal(t, "java.lang.Integer", testMetadataRule.metadata.ParameterType)
+	require.Equal(t, "com.bytebase.domain.Order", testMetadataRule.metadata.ResultType)
+}
@@ -0,0 +1,265 @@
+package lint
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/bytebase/bytebase/backend/plugin/parser/base"
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/configuration"
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper/ast"
+	storepb "github.com/bytebase/bytebase/proto/generated-go/store"
+)
+
+// StatementMetadata is the metadata of the mapper statement, it is built from the statement and the included sql fragments.
+type StatementMetadata struct {
+	// Namespace is the namespace of the mapper which contains the statement.
+	Namespace string
+	// ID is the id of the statement.
+	ID string
+	// Kind is the kind of the statement, can be "select", "insert", "update" or "delete".
+	Kind string
+	// Line is the line of the statement in mybatis mapper xml.
+	Line int
+	// ParameterNames is the sorted unique property names of the #{} parameters.
+	ParameterNames []string
+	// VariableNames is the sorted unique names of the ${} substitutions which are not replaced by the include properties.
+	// The ${} and $!{} in the body of the non-XML language driver are found by scanning the raw text, the comments of
+	// the scripts are not recognized.
+	VariableNames []string
+	// Fragments is the ids of the sql fragments included by the statement directly or indirectly, in the include order.
+	Fragments []string
+	// SQL is the SQL restored from the statement, the parameters are restored to "?".
+	SQL string
+	// RestoreError is the reason why the statement cannot be restored, for example, the included fragment is not
+	// found. The SQL and the metadata derived from it are empty if it is set.
+	RestoreError string
+	// Tables is the sorted unique names of the tables referenced by the restored SQL. They are extracted by the engine
+	// parser if the engine in the FingerprintOptions is known. Otherwise, or if the SQL cannot be parsed, for example,
+	// the statement is the raw text of an unknown language driver, they are found by a lexical heuristic which may
+	// miss the tables or report the false ones.
+	Tables []string
+	// HasLimit is true if the restored SQL limits the number of rows by LIMIT, FETCH FIRST/NEXT or TOP.
+	HasLimit bool
+	// NormalizedSQL is the SQL normalized by NormalizeSQL, it is the same for the statements which only differ in literals.
+	NormalizedSQL string
+	// Fingerprint is the fingerprint of the statement computed with the FingerprintOptions in CheckContext.
+	Fingerprint string
+	// Timeout is the seconds of the timeout attribute, 0 means the timeout is not set.
+	Timeout int
+	// FetchSize is the value of the fetchSize attribute, 0 means the fetch size is not set.
+	FetchSize int
+	// FlushCache is the value of the flushCache attribute, it defaults to true for insert, update and delete, and false for select.
+	FlushCache bool
+	// UseCache is the value of the useCache attribute, it defaults to true for select, and false for the others.
+	UseCache bool
+	// StatementType is the value of the statementType attribute, can be "STATEMENT", "PREPARED" or "CALLABLE",
+	// defaults to "PREPARED".
+	StatementType string
+	// LanguageDriver is the language driver of the statement body, can be "xml", "freemarker", "velocity" or "unknown".
+	LanguageDriver string
+	// RawText is true if the language driver is unknown, the SQL is the raw text of the statement body and the
+	// parameters, variables, tables and limit cannot be recognized reliably.
+	RawText bool
+	// ParameterMap is the id of the legacy <parameterMap> referenced by the parameterMap attribute.
+	ParameterMap string
+	// ParameterMappings is the parameters declared in the referenced <parameterMap> in order, they are bound to the
+	// "?" in the SQL. It is nil if the parameter map is not referenced or cannot be resolved.
+	ParameterMappings []*ParameterMapping
+	// ParameterType is the fully qualified class name of the parameterType attribute, the type aliases are resolved.
+	// It is the type of the referenced <parameterMap> if the parameterType attribute is not set.
+	ParameterType string
+	// ResultType is the fully qualified class name of the resultType attribute, the type aliases are resolved.
+	ResultType string
+}
+
+// ParameterMapping is the parameter declared in the legacy <parameterMap>.
+type ParameterMapping struct {
+	Property string
+	// JavaType is the fully qualified class name of the javaType attribute, the type aliases are resolved.
+	JavaType string
+	JdbcType string
+	// Mode is the mode of the parameter, can be "IN", "OUT" or "INOUT", defaults to "IN".
+	Mode string
+}
+
+// scriptSubstitutionRegexp matches the ${} and $!{} substitutions in the body of the non-XML language driver.
+var scriptSubstitutionRegexp = regexp.MustCompile(`\$!?\{([^}]*)\}`)
+
+// newStatementMetadata builds the metadata of the statement. The statement which cannot be restored still has the
+// metadata which does not depend on the SQL, the restore error is kept in ctx.restoreErr and metadata.RestoreError.
+func newStatementMetadata(ctx *Context) (*StatementMetadata, error) {
+	metadata := &StatementMetadata{
+		Namespace: ctx.Namespace,
+		ID:        ctx.Statement.ID,
+		Kind:      getStatementKind(ctx.Statement.Type),
+		Line:      ctx.Statement.Line,
+	}
+	setExecutionAttributes(metadata, ctx.Statement)
+	metadata.LanguageDriver = string(ctx.Statement.LanguageDriver)
+	metadata.RawText = ctx.Statement.LanguageDriver == ast.LanguageDriverUnknown
+	parameterNames := make(map[string]bool)
+	variableNames := make(map[string]bool)
+	if err := ctx.Walk(func(node ast.Node, _ []ast.Node, properties map[string]string) error {
+		switch n := node.(type) {
+		case *ast.ParameterNode:
+			// The parameter may contain the options, likes #{id,jdbcType=INTEGER}.
+			name, _, _ := strings.Cut(n.Name, ",")
+			parameterNames[strings.TrimSpace(name)] = true
+		case *ast.VariableNode:
+			if _, ok := properties[n.Name]; !ok {
+				variableNames[n.Name] = true
+			}
+		case *ast.ScriptNode:
+			// The body of the non-XML language driver is not parsed into the variable nodes.
+			for _, matches := range scriptSubstitutionRegexp.FindAllStringSubmatch(n.Text, -1) {
+				variableNames[strings.TrimSpace(matches[1])] = true
+			}
+		case *ast.SQLNode:
+			metadata.Fragments = append(metadata.Fragments, n.ID)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	metadata.ParameterType = ctx.typeAliasResolver.Resolve(ctx.Statement.ParameterType)
+	metadata.ResultType = ctx.typeAliasResolver.Resolve(ctx.Statement.ResultType)
+	if ctx.Statement.ParameterMap != "" {
+		metadata.ParameterMap = ctx.Statement.ParameterMap
+		if parameterMapNode, ok := ctx.ParameterMaps[ctx.Statement.ParameterMap]; ok {
+			metadata.ParameterMappings = getParameterMappings(parameterMapNode, ctx.typeAliasResolver)
+			if metadata.ParameterType == "" {
+				metadata.ParameterType = ctx.typeAliasResolver.Resolve(parameterMapNode.Type)
+			}
+			for _, mapping := range metadata.ParameterMappings {
+				parameterNames[mapping.Property] = true
+			}
+		}
+	}
+	metadata.ParameterNames = sortedKeys(parameterNames)
+	metadata.VariableNames = sortedKeys(variableNames)
+
+	sql, err := restoreStatement(ctx)
+	if err != nil {
+		ctx.restoreErr = err
+		metadata.RestoreError = err.Error()
+		return metadata, nil
+	}
+	tokens := scanSQL(sql)
+	metadata.SQL = sql
+	metadata.Tables = getTables(ctx.fingerprintOptions.Engine, sql, tokens, metadata.RawText)
+	metadata.HasLimit = hasLimit(tokens)
+	metadata.NormalizedSQL = normalizeSQLWithFallback(ctx.fingerprintOptions.Engine, sql)
+	metadata.Fingerprint = ctx.fingerprintOptions.Fingerprint(metadata)
+	return metadata, nil
+}
+
+func getParameterMappings(parameterMapNode *ast.ParameterMapNode, typeAliasResolver *configuration.TypeAliasResolver) []*ParameterMapping {
+	mappings := []*ParameterMapping{}
+	for _, parameter := range parameterMapNode.Parameters {
+		mode := strings.ToUpper(strings.TrimSpace(parameter.Mode))
+		if mode == "" {
+			mode = "IN"
+		}
+		mappings = append(mappings, &ParameterMapping{
+			Property: parameter.Property,
+			JavaType: typeAliasResolver.Resolve(parameter.JavaType),
+			JdbcType: parameter.JdbcType,
+			Mode:     mode,
+		})
+	}
+	return mappings
+}
+
+// restoreStatement restores the SQL of the statement with "?" as the placeholder, the trailing semicolon is removed.
+func restoreStatement(ctx *Context) (string, error) {
+	if ctx.restoreErr != nil {
+		return "", ctx.restoreErr
+	}
+	restoreContext := &ast.RestoreContext{
+		SQLMap:                           ctx.SQLMap,
+		Variable:                         make(map[string]string),
+		SQLLastLineToOriginalLineMapping: make(map[int]int),
+		CurrentLastLine:                  1,
+		MaxIncludeDepth:                  ctx.maxIncludeDepth,
+		MaxOutputSize:                    ctx.maxOutputSize,
+	}
+	var sb strings.Builder
+	if err := ctx.Statement.RestoreSQL(restoreContext.WithRestoreDataNodePlaceholder("?"), &sb); err != nil {
+		return "", errors.Wrapf(err, "failed to restore statement %q", ctx.Statement.ID)
+	}
+	return strings.TrimSuffix(strings.TrimSpace(sb.String()), ";"), nil
+}
+
+// setExecutionAttributes sets the execution-tuning attributes with the MyBatis defaults. The values which are not
+// literals, for example, timeout="${query.timeout}" resolved by the configuration properties, are treated as unset.
+func setExecutionAttributes(metadata *StatementMetadata, queryNode *ast.QueryNode) {
+	isSelect := queryNode.Type == ast.QueryNodeTypeSelect
+	metadata.Timeout = parsePositiveInt(queryNode.Timeout)
+	metadata.FetchSize = parsePositiveInt(queryNode.FetchSize)
+	metadata.FlushCache = parseBool(queryNode.FlushCache, !isSelect)
+	metadata.UseCache = parseBool(queryNode.UseCache, isSelect)
+	metadata.StatementType = "PREPARED"
+	switch statementType := strings.ToUpper(strings.TrimSpace(queryNode.StatementType)); statementType {
+	case "STATEMENT", "PREPARED", "CALLABLE":
+		metadata.StatementType = statementType
+	}
+}
+
+func parsePositiveInt(s string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
+}
+
+func parseBool(s string, defaultValue bool) bool {
+	v, err := strconv.ParseBool(strings.TrimSpace(s))
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
+
+func getStatementKind(queryNodeType ast.QueryNodeType) string {
+	switch queryNodeType {
+	case ast.QueryNodeTypeSelect:
+		return "select"
+	case ast.QueryNodeTypeInsert:
+		return "insert"
+	case ast.QueryNodeTypeUpdate:
+		return "update"
+	case ast.QueryNodeTypeDelete:
+		return "delete"
+	}
+	return ""
+}
+
+// getTables returns the tables referenced by the SQL, see StatementMetadata.Tables.
+func getTables(engine storepb.Engine, sql string, tokens []sqlToken, rawText bool) []string {
+	if engine != storepb.Engine_ENGINE_UNSPECIFIED && !rawText {
+		if resources, err := base.ExtractResourceList(engine, "", "", sql); err == nil {
+			tables := make(map[string]bool)
+			for _, resource := range resources {
+				if resource.Table != "" {
+					tables[resource.Pretty()] = true
+				}
+			}
+			return sortedKeys(tables)
+		}
+	}
+	return extractTables(tokens)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,141 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	mysqlparser "github.com/bytebase/bytebase/backend/plugin/parser/mysql"
+	storepb "github.com/bytebase/bytebase/proto/generated-go/store"
+)
+
+// literalPlaceholder is the placeholder of the literals in the normalized SQL.
+const literalPlaceholder = "?"
+
+// listPlaceholder is the placeholder of the collapsed list in the normalized SQL, it is the same as the one
+// produced by the MySQL fingerprint.
+const listPlaceholder = "?+"
+
+// nullPlaceholder is the identifier which protects NULL from being stripped by the MySQL fingerprint.
+const nullPlaceholder = "__bb_null__"
+
+var nullRegexp = regexp.MustCompile(`(?i)\bnull\b`)
+
+// NormalizeSQL normalizes the restored SQL, so the statements differ only in literals, IN lists, keyword case,
+// whitespaces and comments have the same normalized SQL. The normalization:
+//
+//   - strips the string, numeric and boolean literals to "?". NULL is kept, because "IS NULL" and "= ?" are
+//     different predicates.
+//   - collapses the IN lists and the VALUES rows of the placeholders to "(?+)".
+//   - uppercases the keywords, the identifiers are kept as is because their case sensitivity depends on the engine
+//     and the configuration, for example, the table names of MySQL on Linux.
+//
+// For the MySQL compatible engines, the SQL is normalized by the MySQL fingerprint first. The tokens of the
+// normalized SQL are separated by single space.
+func NormalizeSQL(engine storepb.Engine, sql string) (string, error) {
+	switch engine {
+	case storepb.Engine_MYSQL, storepb.Engine_MARIADB, storepb.Engine_OCEANBASE, storepb.Engine_TIDB:
+		// The MySQL fingerprint strips NULL to "?", so it is protected by the placeholder identifier.
+		fingerprint, err := mysqlparser.GetFingerprint(nullRegexp.ReplaceAllString(sql, nullPlaceholder))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get MySQL fingerprint")
+		}
+		sql = strings.ReplaceAll(fingerprint, nullPlaceholder, "NULL")
+	}
+	return strings.Join(normalizeTokens(scanSQL(sql), true /* stripLiterals */), " "), nil
+}
+
+// normalizeSQLWithFallback normalizes the SQL by NormalizeSQL, and falls back to the engine-agnostic normalization of
+// the raw SQL if the engine-specific normalizer fails, for example, the SQL cannot be parsed by the MySQL parser.
+func normalizeSQLWithFallback(engine storepb.Engine, sql string) string {
+	normalized, err := NormalizeSQL(engine, sql)
+	if err != nil {
+		return strings.Join(normalizeTokens(scanSQL(sql), true /* stripLiterals */), " ")
+	}
+	return normalized
+}
+
+// normalizeTokens returns the normalized texts of the tokens, the literals are stripped if stripLiterals is true.
+func normalizeTokens(tokens []sqlToken, stripLiterals bool) []string {
+	var texts []string
+	for _, token := range tokens {
+		switch token.tp {
+		case sqlTokenWord:
+			word := token.text
+			if upper := strings.ToUpper(word); sqlKeywords[upper] {
+				word = upper
+			}
+			if stripLiterals && (word == "TRUE" || word == "FALSE") {
+				word = literalPlaceholder
+			}
+			texts = append(texts, word)
+		case sqlTokenString, sqlTokenNumber:
+			if stripLiterals {
+				texts = append(texts, literalPlaceholder)
+			} else {
+				texts = append(texts, token.text)
+			}
+		default:
+			texts = append(texts, token.text)
+		}
+	}
+	if stripLiterals {
+		texts = collapseLists(texts)
+	}
+	return texts
+}
+
+// collapseLists collapses "IN (?, ?, ?)" to "IN (?+)", and "VALUES (?, ?), (?, ?)" to "VALUES (?+)".
+func collapseLists(texts []string) []string {
+	var result []string
+	for i := 0; i < len(texts); i++ {
+		result = append(result, texts[i])
+		if texts[i] != "IN" && texts[i] != "VALUES" {
+			continue
+		}
+		// Skip the consecutive placeholder lists separated by commas, only one list is allowed after IN.
+		end := i
+		for j := i + 1; j < len(texts); {
+			next := placeholderListEnd(texts, j)
+			if next < 0 {
+				break
+			}
+			end = next
+			if texts[i] == "IN" || next+1 >= len(texts) || texts[next+1] != "," {
+				break
+			}
+			j = next + 2
+		}
+		if end == i {
+			continue
+		}
+		result = append(result, "(", listPlaceholder, ")")
+		i = end
+	}
+	return result
+}
+
+// placeholderListEnd returns the index of the ")" if texts[start:] begins with the parenthesized placeholder list
+// likes "( ? , ? )", otherwise returns -1.
+func placeholderListEnd(texts []string, start int) int {
+	if start >= len(texts) || texts[start] != "(" {
+		return -1
+	}
+	expectPlaceholder := true
+	for i := start + 1; i < len(texts); i++ {
+		switch {
+		case expectPlaceholder && (texts[i] == literalPlaceholder || texts[i] == listPlaceholder):
+			expectPlaceholder = false
+		case !expectPlaceholder && texts[i] == "+" && texts[i-1] == literalPlaceholder:
+			// The "?+" produced by the MySQL fingerprint is scanned as two tokens.
+		case !expectPlaceholder && texts[i] == ",":
+			expectPlaceholder = true
+		case !expectPlaceholder && texts[i] == ")":
+			return i
+		default:
+			return -1
+		}
+	}
+	return -1
+}
@@ -0,0 +1,54 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	storepb "github.com/bytebase/bytebase/proto/generated-go/store"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	testCases := []struct {
+		engine storepb.Engine
+		sql    string
+		want   string
+	}{
+		{
+			engine: storepb.Engine_POSTGRES,
+			sql:    "select *\n\tfrom t /* comment */ where status = 1 and name = 'a''b' and deleted is not null",
+			want:   "SELECT * FROM t WHERE status = ? AND name = ? AND deleted IS NOT NULL",
+		},
+		{
+			engine: storepb.Engine_POSTGRES,
+			sql:    `SELECT "Id" FROM t WHERE id IN (1, 2, 3) AND flag = true AND code IN (SELECT code FROM s)`,
+			want:   `SELECT "Id" FROM t WHERE id IN ( ?+ ) AND flag = ? AND code IN ( SELECT code FROM s )`,
+		},
+		{
+			engine: storepb.Engine_ENGINE_UNSPECIFIED,
+			sql:    "INSERT INTO t (a, b) VALUES (?, 'x'), (?, 'y'), (?, 'z')",
+			want:   "INSERT INTO t ( a , b ) VALUES ( ?+ )",
+		},
+		{
+			engine: storepb.Engine_MYSQL,
+			sql:    "SELECT * FROM `t` WHERE id IN (1, 2, 3) AND name = \"a\" ORDER BY id ASC LIMIT 10, 20",
+			want:   "SELECT * FROM `t` WHERE id IN ( ?+ ) AND name = ? ORDER BY id LIMIT ?",
+		},
+		{
+			engine: storepb.Engine_MYSQL,
+			sql:    "SELECT * FROM t WHERE deleted_at IS NULL AND status = 1",
+			want:   "SELECT * FROM t WHERE deleted_at IS NULL AND status = ?",
+		},
+		{
+			// The identifiers keep their case, the table T and t are different in the case-sensitive engines.
+			engine: storepb.Engine_POSTGRES,
+			sql:    "Select Name From T_User",
+			want:   "SELECT Name FROM T_User",
+		},
+	}
+	for _, tc := range testCases {
+		normalized, err := NormalizeSQL(tc.engine, tc.sql)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, normalized, tc.sql)
+	}
+}
@@ -0,0 +1,186 @@
+package lint
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper"
+	storepb "github.com/bytebase/bytebase/proto/generated-go/store"
+)
+
+const (
+	// defaultPipelineQueueSizePerWorker is the default queue size for each worker between the stages.
+	defaultPipelineQueueSizePerWorker = 4
+	// defaultMaxMapperFileSize is the default max size of a mapper file, it is large enough for the generated mappers.
+	defaultMaxMapperFileSize = 8 * 1024 * 1024
+	// defaultMaxFileOutputSize is the default max total bytes of the SQL restored from the statements in a mapper file.
+	defaultMaxFileOutputSize = 64 * 1024 * 1024
+	// defaultMaxFindingsPerFile is the default max number of findings kept for a mapper file.
+	defaultMaxFindingsPerFile = 1000
+)
+
+// MapperFile is the mybatis mapper xml file to lint.
+type MapperFile struct {
+	// Path is the path of the file, it is only used to identify the file in the result.
+	Path    string
+	Content string
+}
+
+// FileResult is the lint result of a mapper file.
+type FileResult struct {
+	Path     string
+	Findings []*Finding
+	// DroppedFindings is the number of the findings dropped because the file has more than MaxFindingsPerFile
+	// findings, the first MaxFindingsPerFile findings are kept.
+	DroppedFindings int
+	// Err is the error of linting the file, it does not affect the other files.
+	Err error
+}
+
+// ScanFunc scans the mapper files and emits them one by one. The emit blocks if the workers are busy,
+// and returns the error if the pipeline is canceled, the ScanFunc should stop scanning and return the error.
+type ScanFunc func(ctx context.Context, emit func(file *MapperFile) error) error
+
+// ResultFunc handles the lint result of a mapper file, it is called in a single goroutine in completion order.
+// Returning an error cancels the pipeline.
+type ResultFunc func(result *FileResult) error
+
+// PipelineConfig is the config of the lint pipeline. The memory used by the pipeline is bounded by about
+// (Concurrency + 2 * QueueSize) * MaxFileSize for the files, and Concurrency * (CheckContext.MaxFileOutputSize +
+// CheckContext.MaxOutputSize) for the restored SQL, the CheckContext.MaxFileOutputSize defaults to 64 MiB in the
+// pipeline.
+type PipelineConfig struct {
+	// Concurrency is the number of workers parsing, restoring and checking the files, default is the number of CPUs.
+	Concurrency int
+	// QueueSize is the capacity of the queue between the stages, the upstream stage blocks if the queue is full.
+	// Default is 4 times of the concurrency.
+	QueueSize int
+	// MaxFileSize is the max bytes of a mapper file, default is 8 MiB. The larger file is not parsed and
+	// reported in FileResult.Err.
+	MaxFileSize int
+	// MaxFindingsPerFile is the max number of findings kept in FileResult, default is 1000.
+	MaxFindingsPerFile int
+
+	// RuleList is the rule list of the SQL review policy.
+	RuleList []*storepb.SQLReviewRule
+	// CheckContext is the context shared by all files, the rules must not modify it.
+	CheckContext CheckContext
+}
+
+// RunPipeline lints the mapper files emitted by scan with the bounded worker pool, and streams the results to handle.
+// It returns after all emitted files are handled, or the first error of scan and handle.
+func RunPipeline(ctx context.Context, config PipelineConfig, scan ScanFunc, handle ResultFunc) error {
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = concurrency * defaultPipelineQueueSizePerWorker
+	}
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = defaultMaxMapperFileSize
+	}
+	if config.MaxFindingsPerFile <= 0 {
+		config.MaxFindingsPerFile = defaultMaxFindingsPerFile
+	}
+	if config.CheckContext.MaxFileOutputSize <= 0 {
+		config.CheckContext.MaxFileOutputSize = defaultMaxFileOutputSize
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	files := make(chan *MapperFile, queueSize)
+	results := make(chan *FileResult, queueSize)
+
+	var scanErr error
+	go func() {
+		defer close(files)
+		scanErr = scan(ctx, func(file *MapperFile) error {
+			select {
+			case files <- file:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range files {
+				if ctx.Err() != nil {
+					// Drain the files to unblock the scanner without linting them.
+					continue
+				}
+				result := lintFile(config, file)
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					// Drain the files to unblock the scanner.
+					continue
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var handleErr error
+	for result := range results {
+		if handleErr != nil {
+			continue
+		}
+		if err := handle(result); err != nil {
+			handleErr = err
+			cancel()
+		}
+	}
+	// The results channel is closed after the files channel is closed, so the scanErr is safe to read.
+	if handleErr != nil {
+		return handleErr
+	}
+	if scanErr != nil {
+		return errors.Wrapf(scanErr, "failed to scan mapper files")
+	}
+	return nil
+}
+
+// lintFile parses the mapper file, and checks the statements with the rules.
+func lintFile(config PipelineConfig, file *MapperFile) (result *FileResult) {
+	result = &FileResult{Path: file.Path}
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			result.Findings = nil
+			result.Err = errors.Errorf("panic in linting mapper file %q, because: %v", file.Path, panicErr)
+		}
+	}()
+	if len(file.Content) > config.MaxFileSize {
+		result.Err = errors.Errorf("the size of mapper file %q is %d bytes, which exceeds the limit %d bytes", file.Path, len(file.Content), config.MaxFileSize)
+		return result
+	}
+	root, err := mapper.NewParser(file.Content).Parse()
+	if err != nil {
+		result.Err = errors.Wrapf(err, "failed to parse mapper file %q", file.Path)
+		return result
+	}
+	findings, err := Check(root, config.RuleList, config.CheckContext)
+	if err != nil {
+		result.Err = errors.Wrapf(err, "failed to check mapper file %q", file.Path)
+		return result
+	}
+	if len(findings) > config.MaxFindingsPerFile {
+		result.DroppedFindings = len(findings) - config.MaxFindingsPerFile
+		findings = findings[:config.MaxFindingsPerFile]
+	}
+	result.Findings = findings
+	return result
+}
@@ -0,0 +1,129 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	storepb "github.com/bytebase/bytebase/proto/generated-go/store"
+)
+
+func TestRunPipeline(t *testing.T) {
+	ruleList := []*storepb.SQLReviewRule{
+		{
+			Type:  string(RuleNoDollarSubstitution),
+			Level: storepb.SQLReviewRuleLevel_ERROR,
+		},
+	}
+	const fileCount = 100
+	scan := func(ctx context.Context, emit func(file *MapperFile) error) error {
+		for i := 0; i < fileCount; i++ {
+			content := fmt.Sprintf(`<mapper namespace="com.bytebase.test%d">
+	<select id="selectByOrder">
+		SELECT * FROM t ORDER BY ${orderBy}
+	</select>
+</mapper>`, i)
+			switch i {
+			case 10:
+				content = `<mapper namespace="com.bytebase.invalid"><select id="selectByOrder">`
+			case 20:
+				content = fmt.Sprintf(`<mapper namespace="com.bytebase.large"><select id="selectAll">SELECT * FROM t%s</select></mapper>`, strings.Repeat(" ", 1024))
+			}
+			if err := emit(&MapperFile{Path: fmt.Sprintf("mapper%d.xml", i), Content: content}); err != nil {
+				return err
+			}
+		}
+		return ctx.Err()
+	}
+
+	config := PipelineConfig{
+		Concurrency: 4,
+		QueueSize:   2,
+		MaxFileSize: 1024,
+		RuleList:    ruleList,
+	}
+	results := make(map[string]*FileResult)
+	err := RunPipeline(context.Background(), config, scan, func(result *FileResult) error {
+		results[result.Path] = result
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, results, fileCount)
+	for i := 0; i < fileCount; i++ {
+		result := results[fmt.Sprintf("mapper%d.xml", i)]
+		switch i {
+		case 10:
+			require.ErrorContains(t, result.Err, "failed to parse mapper file")
+		case 20:
+			require.ErrorContains(t, result.Err, "exceeds the limit 1024 bytes")
+		default:
+			require.NoError(t, result.Err)
+			require.Len(t, result.Findings, 1)
+			require.Equal(t, fmt.Sprintf("com.bytebase.test%d", i), result.Findings[0].Namespace)
+		}
+	}
+
+	// The findings and the restored SQL of a file are bounded.
+	largeMapperScan := func(_ context.Context, emit func(file *MapperFile) error) error {
+		var sb strings.Builder
+		_, _ = sb.WriteString(`<mapper namespace="com.bytebase.large">`)
+		for i := 0; i < 10; i++ {
+			_, _ = fmt.Fprintf(&sb, `<select id="select%d">SELECT * FROM t ORDER BY ${orderBy}</select>`, i)
+		}
+		_, _ = sb.WriteString(`</mapper>`)
+		return emit(&MapperFile{Path: "large.xml", Content: sb.String()})
+	}
+	var largeResult *FileResult
+	err = RunPipeline(context.Background(), PipelineConfig{
+		MaxFindingsPerFile: 5,
+		RuleList:           ruleList,
+		CheckContext:       CheckContext{MaxFileOutputSize: 100},
+	}, largeMapperScan, func(result *FileResult) error {
+		largeResult = result
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, largeResult.Err)
+	// Each statement restores 26 bytes, the statements after the fourth one exceed the file output size, they are
+	// reported by both the expansion limit and the no-dollar-substitution.
+	require.Len(t, largeResult.Findings, 5)
+	require.Equal(t, 16-5, largeResult.DroppedFindings)
+	require.Equal(t, RuleNoDollarSubstitution, largeResult.Findings[3].Rule)
+	require.Equal(t, RuleExpansionLimit, largeResult.Findings[4].Rule)
+	require.Contains(t, largeResult.Findings[4].Content, "restored SQL of the mapper file exceeds the max file output size 100 bytes")
+
+	// The canceled pipeline does not lint the queued files.
+	canceledCtx, cancelPipeline := context.WithCancel(context.Background())
+	cancelPipeline()
+	linted := 0
+	err = RunPipeline(canceledCtx, config, scan, func(*FileResult) error {
+		linted++
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, linted)
+
+	// The handle error cancels the pipeline, and the scanner stops emitting.
+	handled := 0
+	err = RunPipeline(context.Background(), config, scan, func(*FileResult) error {
+		handled++
+		if handled == 5 {
+			return errors.New("stop")
+		}
+		return nil
+	})
+	require.EqualError(t, err, "stop")
+	require.Less(t, handled, fileCount)
+
+	// The scan error is returned.
+	err = RunPipeline(context.Background(), config, func(context.Context, func(file *MapperFile) error) error {
+		return errors.New("permission denied")
+	}, func(*FileResult) error {
+		return nil
+	})
+	require.EqualError(t, err, "failed to scan mapper files: permission denied")
+}
@@ -0,0 +1,176 @@
+package lint
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/pkg/errors"
+)
+
+var (
+	_ Rule = (*CELExpressionRule)(nil)
+)
+
+func init() {
+	Register(RuleCELExpression, &CELExpressionRule{})
+}
+
+// celExpressionSizeLimit is the limit of the user-defined CEL expression size.
+const celExpressionSizeLimit = 1024 * 1024
+
+// StatementCELAttributes are the variables when evaluating the user-defined mapper lint rule.
+var StatementCELAttributes = []cel.EnvOption{
+	cel.Variable("statement.namespace", cel.StringType),
+	cel.Variable("statement.id", cel.StringType),
+	// "select", "insert", "update" or "delete".
+	cel.Variable("statement.kind", cel.StringType),
+	cel.Variable("statement.sql", cel.StringType),
+	cel.Variable("statement.normalized_sql", cel.StringType),
+	cel.Variable("statement.tables", cel.ListType(cel.StringType)),
+	cel.Variable("statement.parameter_names", cel.ListType(cel.StringType)),
+	cel.Variable("statement.variable_names", cel.ListType(cel.StringType)),
+	cel.Variable("statement.fragments", cel.ListType(cel.StringType)),
+	cel.Variable("statement.has_dollar_substitution", cel.BoolType),
+	cel.Variable("statement.has_limit", cel.BoolType),
+	// 0 means the timeout or fetch size is not set.
+	cel.Variable("statement.timeout", cel.IntType),
+	cel.Variable("statement.fetch_size", cel.IntType),
+	cel.Variable("statement.flush_cache", cel.BoolType),
+	cel.Variable("statement.use_cache", cel.BoolType),
+	// "STATEMENT", "PREPARED" or "CALLABLE".
+	cel.Variable("statement.statement_type", cel.StringType),
+	// "xml", "freemarker", "velocity" or "unknown".
+	cel.Variable("statement.language_driver", cel.StringType),
+	cel.Variable("statement.raw_text", cel.BoolType),
+	cel.Variable("statement.parameter_map", cel.StringType),
+	// The fully qualified class names, the type aliases are resolved.
+	cel.Variable("statement.parameter_type", cel.StringType),
+	cel.Variable("statement.result_type", cel.StringType),
+	cel.ParserExpressionSizeLimit(celExpressionSizeLimit),
+}
+
+// CELExpressionRule is the user-defined rule written in CEL expression over the statement metadata.
+// The rule payload likes:
+//
+//	{
+//	  "name": "orders-require-limit",
+//	  "expression": "statement.kind == \"select\" && \"orders\" in statement.tables && !statement.has_limit",
+//	  "title": "orders.require-limit",
+//	  "message": "SELECT on orders must have LIMIT"
+//	}
+//
+// The expression must be evaluated to a boolean, and true means the statement violates the rule. The name is required,
+// the findings are reported as "mybatis.cel-expression/<name>", so each user-defined rule is suppressed by the
+// bb:ignore directive, recorded in the baseline and overridden by the SQL review config override separately.
+type CELExpressionRule struct {
+	// programs caches the compiled program of the expression, key is the expression.
+	programs sync.Map
+}
+
+// Check checks the statement with the CEL expression in the rule payload.
+func (r *CELExpressionRule) Check(ctx *Context) ([]*Finding, error) {
+	expression, ok := ctx.Param("expression")
+	if !ok || expression == "" {
+		return nil, errors.Errorf("the expression of rule %q is required", ctx.Rule.Type)
+	}
+	prg, err := r.getProgram(expression)
+	if err != nil {
+		return nil, err
+	}
+	metadata := ctx.Metadata
+	// Most expressions are over the restored SQL, so the statement which cannot be restored is skipped to avoid the
+	// false positives, it is reported by RuleRestoreFailure.
+	if metadata.RestoreError != "" {
+		return nil, nil
+	}
+	res, _, err := prg.Eval(map[string]any{
+		"statement.namespace":               metadata.Namespace,
+		"statement.id":                      metadata.ID,
+		"statement.kind":                    metadata.Kind,
+		"statement.sql":                     metadata.SQL,
+		"statement.normalized_sql":          metadata.NormalizedSQL,
+		"statement.tables":                  nonNilStrings(metadata.Tables),
+		"statement.parameter_names":         nonNilStrings(metadata.ParameterNames),
+		"statement.variable_names":          nonNilStrings(metadata.VariableNames),
+		"statement.fragments":               nonNilStrings(metadata.Fragments),
+		"statement.has_dollar_substitution": len(metadata.VariableNames) > 0,
+		"statement.has_limit":               metadata.HasLimit,
+		"statement.timeout":                 metadata.Timeout,
+		"statement.fetch_size":              metadata.FetchSize,
+		"statement.flush_cache":             metadata.FlushCache,
+		"statement.use_cache":               metadata.UseCache,
+		"statement.statement_type":          metadata.StatementType,
+		"statement.language_driver":         metadata.LanguageDriver,
+		"statement.raw_text":                metadata.RawText,
+		"statement.parameter_map":           metadata.ParameterMap,
+		"statement.parameter_type":          metadata.ParameterType,
+		"statement.result_type":             metadata.ResultType,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to evaluate expression %q", expression)
+	}
+	violated, ok := res.Value().(bool)
+	if !ok {
+		return nil, errors.Errorf("expect bool result for expression %q, but got %v", expression, res.Type())
+	}
+	if !violated {
+		return nil, nil
+	}
+
+	title, _ := ctx.Param("title")
+	message, ok := ctx.Param("message")
+	if !ok || message == "" {
+		message = fmt.Sprintf("\"%s\" matches the expression %q", metadata.ID, expression)
+	}
+	return []*Finding{
+		{
+			Title:   title,
+			Content: message,
+		},
+	}, nil
+}
+
+func (r *CELExpressionRule) getProgram(expression string) (cel.Program, error) {
+	if prg, ok := r.programs.Load(expression); ok {
+		return prg.(cel.Program), nil
+	}
+	prg, err := compileStatementExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	r.programs.Store(expression, prg)
+	return prg, nil
+}
+
+// ValidateStatementExpression validates the CEL expression of the user-defined mapper lint rule.
+func ValidateStatementExpression(expression string) error {
+	_, err := compileStatementExpression(expression)
+	return err
+}
+
+func compileStatementExpression(expression string) (cel.Program, error) {
+	e, err := cel.NewEnv(StatementCELAttributes...)
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := e.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, errors.Wrapf(issues.Err(), "failed to compile expression %q", expression)
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, errors.Errorf("expect bool result for expression %q, but got %v", expression, ast.OutputType())
+	}
+	prg, err := e.Program(ast)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create program for expression %q", expression)
+	}
+	return prg, nil
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,46 @@
+package lint
+
+import (
+	"fmt"
+)
+
+var (
+	_ Rule = (*DuplicateStatementRule)(nil)
+)
+
+func init() {
+	Register(RuleDuplicateStatement, &DuplicateStatementRule{})
+}
+
+// DuplicateStatementRule is the rule reporting the statement whose normalized SQL is the same as a statement declared
+// before it in the mapper, so the statements differ only in literals, keyword case, whitespaces and comments are
+// duplicates. The statements which cannot be restored or are the raw text of an unknown language driver are skipped.
+type DuplicateStatementRule struct {
+}
+
+// Check checks whether the statement duplicates a statement declared before it.
+func (*DuplicateStatementRule) Check(ctx *Context) ([]*Finding, error) {
+	metadata := ctx.Metadata
+	if !isComparable(metadata) {
+		return nil, nil
+	}
+	for _, other := range ctx.MapperMetadata {
+		if other == metadata {
+			// Only the statements declared before are compared, so the first statement is not reported.
+			break
+		}
+		if !isComparable(other) || other.NormalizedSQL != metadata.NormalizedSQL {
+			continue
+		}
+		return []*Finding{
+			{
+				Content: fmt.Sprintf("\"%s\" duplicates \"%s\" at line %d, the normalized SQL of them is the same", metadata.ID, other.ID, other.Line),
+			},
+		}, nil
+	}
+	return nil, nil
+}
+
+func isComparable(metadata *StatementMetadata) bool {
+	return metadata.RestoreError == "" && !metadata.RawText && metadata.NormalizedSQL != ""
+}
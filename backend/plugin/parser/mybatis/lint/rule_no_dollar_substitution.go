@@ -0,0 +1,53 @@
+package lint
+
+import (
+	"fmt"
+
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper/ast"
+)
+
+var (
+	_ Rule = (*NoDollarSubstitutionRule)(nil)
+)
+
+func init() {
+	Register(RuleNoDollarSubstitution, &NoDollarSubstitutionRule{})
+}
+
+// NoDollarSubstitutionRule is the rule checking for no ${} substitution in the statement.
+type NoDollarSubstitutionRule struct {
+}
+
+// Check checks for no ${} substitution in the statement.
+// The ${} whose name is defined by the property of the outer include element is replaced when the mapper is loaded, so it is allowed.
+// The body of the velocity, freemarker or unknown language driver is not parsed into the variable nodes, the ${} in
+// it is read from the VariableNames of the statement metadata.
+func (*NoDollarSubstitutionRule) Check(ctx *Context) ([]*Finding, error) {
+	var findings []*Finding
+	if err := ctx.Walk(func(node ast.Node, _ []ast.Node, properties map[string]string) error {
+		if scriptNode, ok := node.(*ast.ScriptNode); ok {
+			for _, name := range ctx.Metadata.VariableNames {
+				findings = append(findings, &Finding{
+					Content: fmt.Sprintf("\"%s\" uses ${%s} substitution of the %s language driver which is vulnerable to SQL injection, bind the parameter instead", ctx.Statement.ID, name, scriptNode.Driver),
+					Node:    scriptNode,
+				})
+			}
+			return nil
+		}
+		variableNode, ok := node.(*ast.VariableNode)
+		if !ok {
+			return nil
+		}
+		if _, ok := properties[variableNode.Name]; ok {
+			return nil
+		}
+		findings = append(findings, &Finding{
+			Content: fmt.Sprintf("\"%s\" uses ${%s} substitution which is vulnerable to SQL injection, use #{%s} instead", ctx.Statement.ID, variableNode.Name, variableNode.Name),
+			Node:    variableNode,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
@@ -0,0 +1,163 @@
+package lint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper/ast"
+)
+
+var (
+	_ Rule = (*RequireTimeoutRule)(nil)
+)
+
+func init() {
+	Register(RuleRequireTimeout, &RequireTimeoutRule{})
+}
+
+const (
+	// defaultMaxReferencedTables is the default max number of tables referenced by the statement which is not
+	// expensive.
+	defaultMaxReferencedTables = 3
+)
+
+// RequireTimeoutRule is the rule requiring the timeout attribute, and optionally the fetchSize attribute, on the
+// expensive statements. The rule payload likes:
+//
+//	{
+//	  "max-referenced-tables": 3,
+//	  "check-no-limit": false,
+//	  "check-foreach-in-list": true,
+//	  "require-fetch-size": false
+//	}
+//
+// The statement is expensive if it references more than "max-referenced-tables" distinct tables, or it is a SELECT
+// without LIMIT and "check-no-limit" is true, or it builds the IN list by <foreach> and "check-foreach-in-list" is
+// true. The referenced tables are all the tables in StatementMetadata.Tables, including the ones in the FROM list,
+// the JOIN clauses and the sub-queries. The "check-no-limit" is off by default, because most SELECT statements
+// without LIMIT are the lookups by the keys.
+// If "require-fetch-size" is true, the expensive SELECT also requires the fetchSize attribute.
+type RequireTimeoutRule struct {
+}
+
+// Check checks the timeout and fetchSize attributes of the expensive statement.
+func (*RequireTimeoutRule) Check(ctx *Context) ([]*Finding, error) {
+	maxReferencedTables, err := getIntParam(ctx, "max-referenced-tables", defaultMaxReferencedTables)
+	if err != nil {
+		return nil, err
+	}
+	checkNoLimit, err := getBoolParam(ctx, "check-no-limit", false)
+	if err != nil {
+		return nil, err
+	}
+	checkForEachInList, err := getBoolParam(ctx, "check-foreach-in-list", true)
+	if err != nil {
+		return nil, err
+	}
+	requireFetchSize, err := getBoolParam(ctx, "require-fetch-size", false)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := ctx.Metadata
+	// The tables and the limit are unknown if the statement cannot be restored.
+	if metadata.RestoreError != "" {
+		return nil, nil
+	}
+	var reasons []string
+	if len(metadata.Tables) > maxReferencedTables {
+		reasons = append(reasons, fmt.Sprintf("it references %d tables which is more than %d", len(metadata.Tables), maxReferencedTables))
+	}
+	if checkNoLimit && metadata.Kind == "select" && !metadata.HasLimit {
+		reasons = append(reasons, "it has no LIMIT")
+	}
+	if checkForEachInList {
+		hasForEachInList, err := hasForEachInList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if hasForEachInList {
+			reasons = append(reasons, "it builds the IN list by <foreach>")
+		}
+	}
+	if len(reasons) == 0 {
+		return nil, nil
+	}
+
+	// The attribute value may be resolved by the configuration properties, likes timeout="${query.timeout}",
+	// so we only check whether the attribute is set.
+	var missingAttributes []string
+	if strings.TrimSpace(ctx.Statement.Timeout) == "" {
+		missingAttributes = append(missingAttributes, "timeout")
+	}
+	if requireFetchSize && metadata.Kind == "select" && strings.TrimSpace(ctx.Statement.FetchSize) == "" {
+		missingAttributes = append(missingAttributes, "fetchSize")
+	}
+	if len(missingAttributes) == 0 {
+		return nil, nil
+	}
+	attribute := "attribute"
+	if len(missingAttributes) > 1 {
+		attribute = "attributes"
+	}
+	return []*Finding{
+		{
+			Content: fmt.Sprintf("\"%s\" is expensive because %s, set the %s %s to bound it", metadata.ID, strings.Join(reasons, " and "), strings.Join(missingAttributes, " and "), attribute),
+		},
+	}, nil
+}
+
+// hasForEachInList returns true if the statement has the <foreach> building the IN list, likes
+// "id IN <foreach open="(" ...>" or "<foreach open="id IN (" ...>".
+func hasForEachInList(ctx *Context) (bool, error) {
+	found := false
+	// lastText is the last text before the current node in the document order.
+	lastText := ""
+	if err := ctx.Walk(func(node ast.Node, _ []ast.Node, _ map[string]string) error {
+		switch n := node.(type) {
+		case *ast.TextNode:
+			if trimmed := strings.TrimSpace(n.Text); trimmed != "" {
+				lastText = trimmed
+			}
+		case *ast.ParameterNode, *ast.VariableNode:
+			lastText = ""
+		case *ast.ForEachNode:
+			// The foreach opens the IN list if the text before the items ends with "IN (".
+			tokens := scanSQL(lastText + " " + n.Open)
+			if len(tokens) >= 2 && tokens[len(tokens)-2].isKeyword("IN") && tokens[len(tokens)-1].text == "(" {
+				found = true
+			}
+		}
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	return found, nil
+}
+
+func getIntParam(ctx *Context, name string, defaultValue int) (int, error) {
+	value, ok := ctx.Param(name)
+	if !ok {
+		return defaultValue, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.Errorf("invalid integer %q of parameter %q for rule %q", value, name, ctx.Rule.Type)
+	}
+	return v, nil
+}
+
+func getBoolParam(ctx *Context, name string, defaultValue bool) (bool, error) {
+	value, ok := ctx.Param(name)
+	if !ok {
+		return defaultValue, nil
+	}
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.Errorf("invalid boolean %q of parameter %q for rule %q", value, name, ctx.Rule.Type)
+	}
+	return v, nil
+}
@@ -0,0 +1,233 @@
+package lint
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// sqlTokenType is the type of the token scanned from the restored SQL.
+type sqlTokenType int
+
+const (
+	// sqlTokenWord is the keyword or the unquoted identifier.
+	sqlTokenWord sqlTokenType = iota
+	// sqlTokenQuotedIdentifier is the identifier quoted by backtick, double quote or brackets.
+	sqlTokenQuotedIdentifier
+	// sqlTokenString is the string literal quoted by single quote.
+	sqlTokenString
+	// sqlTokenNumber is the numeric literal.
+	sqlTokenNumber
+	// sqlTokenPunctuation is the other single character, likes '(', ',', '?'.
+	sqlTokenPunctuation
+)
+
+type sqlToken struct {
+	tp   sqlTokenType
+	text string
+}
+
+// scanSQL scans the SQL into tokens on a best-effort basis, the whitespaces and comments are skipped.
+// It knows nothing about the engine-specific syntax, so it only serves the lexical analysis of mapper lint rules.
+func scanSQL(sql string) []sqlToken {
+	runes := []rune(sql)
+	var tokens []sqlToken
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case r == '\'' || r == '"' || r == '`' || r == '[':
+			closeRune := r
+			if r == '[' {
+				closeRune = ']'
+			}
+			start := i
+			i++
+			for i < len(runes) {
+				if runes[i] == closeRune {
+					// The doubled quote is the escaped quote.
+					if i+1 < len(runes) && runes[i+1] == closeRune && closeRune != ']' {
+						i += 2
+						continue
+					}
+					break
+				}
+				if runes[i] == '\\' && closeRune == '\'' {
+					i++
+				}
+				i++
+			}
+			i++
+			if i > len(runes) {
+				i = len(runes)
+			}
+			tp := sqlTokenQuotedIdentifier
+			if r == '\'' {
+				tp = sqlTokenString
+			}
+			tokens = append(tokens, sqlToken{tp: tp, text: string(runes[start:i])})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || unicode.IsLetter(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{tp: sqlTokenNumber, text: string(runes[start:i])})
+		case unicode.IsLetter(r) || r == '_' || r == '$' || r == '@' || r == '#':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$' || runes[i] == '@' || runes[i] == '#') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{tp: sqlTokenWord, text: string(runes[start:i])})
+		default:
+			tokens = append(tokens, sqlToken{tp: sqlTokenPunctuation, text: string(r)})
+			i++
+		}
+	}
+	return tokens
+}
+
+// isKeyword returns true if the token is the word equal to the keyword case-insensitively.
+func (t sqlToken) isKeyword(keyword string) bool {
+	return t.tp == sqlTokenWord && strings.EqualFold(t.text, keyword)
+}
+
+// tableClauseKeywords is the keywords which are followed by the table name.
+var tableClauseKeywords = map[string]bool{
+	"FROM":   true,
+	"JOIN":   true,
+	"INTO":   true,
+	"UPDATE": true,
+	"TABLE":  true,
+	"USING":  true,
+}
+
+// tableListTerminators is the keywords which terminate the comma separated table list after FROM.
+var tableListTerminators = map[string]bool{
+	"WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true, "LIMIT": true, "OFFSET": true, "FETCH": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "MINUS": true, "FOR": true, "SET": true, "VALUES": true,
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "CROSS": true, "NATURAL": true,
+	"ON": true, "WINDOW": true, "RETURNING": true, "SELECT": true, "STRAIGHT_JOIN": true,
+}
+
+// fromArgumentFunctions is the functions whose arguments contain FROM, likes EXTRACT(YEAR FROM col), the FROM in
+// them is not followed by the table name.
+var fromArgumentFunctions = map[string]bool{
+	"EXTRACT":   true,
+	"SUBSTRING": true,
+	"SUBSTR":    true,
+	"TRIM":      true,
+	"POSITION":  true,
+	"OVERLAY":   true,
+}
+
+// extractTables extracts the sorted unique table names following FROM, JOIN, INTO and UPDATE in the SQL. It is the
+// lexical heuristic for the SQL which cannot be parsed by the engine parser.
+func extractTables(tokens []sqlToken) []string {
+	tables := make(map[string]bool)
+	// inFromArgument is the stack of the parentheses, the element is true if the parenthesis is the arguments of the
+	// functions in fromArgumentFunctions.
+	var inFromArgument []bool
+	for i := 0; i < len(tokens); i++ {
+		switch {
+		case tokens[i].tp == sqlTokenPunctuation && tokens[i].text == "(":
+			inFromArgument = append(inFromArgument, i > 0 && tokens[i-1].tp == sqlTokenWord && fromArgumentFunctions[strings.ToUpper(tokens[i-1].text)])
+			continue
+		case tokens[i].tp == sqlTokenPunctuation && tokens[i].text == ")":
+			if len(inFromArgument) > 0 {
+				inFromArgument = inFromArgument[:len(inFromArgument)-1]
+			}
+			continue
+		}
+		if tokens[i].tp != sqlTokenWord || !tableClauseKeywords[strings.ToUpper(tokens[i].text)] {
+			continue
+		}
+		if len(inFromArgument) > 0 && inFromArgument[len(inFromArgument)-1] {
+			continue
+		}
+		isFrom := tokens[i].isKeyword("FROM")
+		i++
+		for i < len(tokens) {
+			name, next := readQualifiedName(tokens, i)
+			if name == "" {
+				// It may be a sub-query, the tables in it are found by the outer loop.
+				i--
+				break
+			}
+			tables[name] = true
+			i = next
+			if !isFrom {
+				i--
+				break
+			}
+			// Skip the alias, and continue reading the next table in the comma separated table list.
+			for i < len(tokens) && !(tokens[i].tp == sqlTokenPunctuation && tokens[i].text == ",") {
+				if (tokens[i].tp == sqlTokenWord && tableListTerminators[strings.ToUpper(tokens[i].text)]) || tokens[i].text == ")" || tokens[i].text == ";" {
+					break
+				}
+				i++
+			}
+			if i >= len(tokens) || tokens[i].text != "," {
+				i--
+				break
+			}
+			i++
+		}
+	}
+	var result []string
+	for table := range tables {
+		result = append(result, table)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// readQualifiedName reads the qualified name likes `db`.`table` or schema.table from tokens[i],
+// returns the name with the quotes removed and the index of the token after the name.
+func readQualifiedName(tokens []sqlToken, i int) (string, int) {
+	var parts []string
+	for i < len(tokens) {
+		token := tokens[i]
+		switch token.tp {
+		case sqlTokenWord:
+			if tableListTerminators[strings.ToUpper(token.text)] {
+				return strings.Join(parts, "."), i
+			}
+			parts = append(parts, token.text)
+		case sqlTokenQuotedIdentifier:
+			parts = append(parts, strings.Trim(token.text, "`\"[]"))
+		default:
+			return strings.Join(parts, "."), i
+		}
+		i++
+		if i < len(tokens) && tokens[i].text == "." {
+			i++
+			continue
+		}
+		break
+	}
+	return strings.Join(parts, "."), i
+}
+
+// hasLimit returns true if the SQL limits the number of rows by LIMIT, FETCH FIRST/NEXT or TOP.
+func hasLimit(tokens []sqlToken) bool {
+	for i, token := range tokens {
+		if token.isKeyword("LIMIT") || token.isKeyword("TOP") {
+			return true
+		}
+		if token.isKeyword("FETCH") && i+1 < len(tokens) && (tokens[i+1].isKeyword("FIRST") || tokens[i+1].isKeyword("NEXT")) {
+			return true
+		}
+	}
+	return false
+}
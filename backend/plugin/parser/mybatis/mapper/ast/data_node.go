@@ -25,6 +25,9 @@ func (n *TextNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
 	if len(n.Text) == 0 {
 		return nil
 	}
+	if err := ctx.grow(len(n.Text)); err != nil {
+		return err
+	}
 	for _, b := range []byte(n.Text) {
 		if b == '\n' {
 			ctx.CurrentLastLine++
@@ -51,6 +54,9 @@ type ParameterNode struct {
 
 // RestoreSQL implements Node interface, parameter node will always be restored to ctx.RestoreDataNodePlaceholder.
 func (*ParameterNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
+	if err := ctx.grow(len(ctx.RestoreDataNodePlaceholder)); err != nil {
+		return err
+	}
 	if _, err := w.Write([]byte(ctx.RestoreDataNodePlaceholder)); err != nil {
 		return err
 	}
@@ -73,14 +79,15 @@ type VariableNode struct {
 
 // RestoreSQL implements Node interface, variable node will always be restored to ctx.RestoreDataNodePlaceholder.
 func (v *VariableNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
-	if value, ok := ctx.Variable[v.Name]; ok {
-		if _, err := w.Write([]byte(value)); err != nil {
-			return err
-		}
-	} else {
-		if _, err := w.Write([]byte(ctx.RestoreDataNodePlaceholder)); err != nil {
-			return err
-		}
+	value, ok := ctx.Variable[v.Name]
+	if !ok {
+		value = ctx.RestoreDataNodePlaceholder
+	}
+	if err := ctx.grow(len(value)); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(value)); err != nil {
+		return err
 	}
 	return nil
 }
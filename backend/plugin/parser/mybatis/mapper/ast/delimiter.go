@@ -0,0 +1,98 @@
+// Package ast defines the abstract syntax tree of mybatis mapper xml.
+package ast
+
+import (
+	"io"
+	"strings"
+)
+
+// StatementDelimiter is the delimiter written after each restored statement, so the restored mapper can be fed into
+// the batch parser of the engine directly.
+type StatementDelimiter string
+
+const (
+	// StatementDelimiterSemicolonNewline writes ";" and a newline after each statement, it is the default delimiter.
+	StatementDelimiterSemicolonNewline StatementDelimiter = ";\n"
+	// StatementDelimiterSemicolon writes ";" after each statement, the statements are in the same line.
+	StatementDelimiterSemicolon StatementDelimiter = ";"
+	// StatementDelimiterGo writes the GO batch separator in a separate line after each statement, it is for SQL Server.
+	StatementDelimiterGo StatementDelimiter = "GO"
+	// StatementDelimiterNone writes only a newline after each statement.
+	StatementDelimiterNone StatementDelimiter = "none"
+)
+
+// WithStatementDelimiter sets the delimiter written after each restored statement, the default is
+// StatementDelimiterSemicolonNewline. The line mapping is meaningful only if the delimiter contains a newline.
+func (r *RestoreContext) WithStatementDelimiter(delimiter StatementDelimiter) *RestoreContext {
+	r.StatementDelimiter = delimiter
+	return r
+}
+
+// writeStatementDelimiter writes the delimiter after the trimmed statement. The semicolon is not written if the
+// statement already ends with it, and it is written in a new line if the statement ends with the line comment,
+// otherwise the semicolon would be the part of the comment.
+func writeStatementDelimiter(ctx *RestoreContext, w io.Writer, trimmed string) error {
+	var delimiter string
+	switch ctx.StatementDelimiter {
+	case StatementDelimiterSemicolon:
+		delimiter = semicolonDelimiter(trimmed, "")
+	case StatementDelimiterGo:
+		delimiter = "\nGO\n"
+	case StatementDelimiterNone:
+		delimiter = "\n"
+	default:
+		delimiter = semicolonDelimiter(trimmed, "\n")
+	}
+	if _, err := w.Write([]byte(delimiter)); err != nil {
+		return err
+	}
+	// The line mapping records the last line of the statement, so the newlines after the statement are counted later.
+	if strings.HasPrefix(delimiter, "\n;") {
+		ctx.CurrentLastLine++
+	}
+	return nil
+}
+
+func semicolonDelimiter(trimmed string, suffix string) string {
+	if strings.HasSuffix(trimmed, ";") {
+		return suffix
+	}
+	if endsWithLineComment(trimmed) {
+		return "\n;" + suffix
+	}
+	return ";" + suffix
+}
+
+// statementDelimiterLines returns the number of the newlines in the delimiter written after the last line of the
+// statement.
+func statementDelimiterLines(ctx *RestoreContext) int {
+	switch ctx.StatementDelimiter {
+	case StatementDelimiterSemicolon:
+		return 0
+	case StatementDelimiterGo:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// endsWithLineComment returns true if the last line of the statement has the "--" line comment which is not in
+// the quoted string or identifier.
+func endsWithLineComment(statement string) bool {
+	lastLine := statement[strings.LastIndex(statement, "\n")+1:]
+	var quote byte
+	for i := 0; i < len(lastLine); i++ {
+		c := lastLine[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '-' && i+1 < len(lastLine) && lastLine[i+1] == '-':
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+// Package ast defines the abstract syntax tree of mybatis mapper xml.
+package ast
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// DirectivePrefix is the prefix of the bytebase directive written in the xml comment.
+const DirectivePrefix = "bb:"
+
+// DirectiveKind is the kind of the bytebase directive.
+type DirectiveKind string
+
+const (
+	// DirectiveKindIgnore represents the directive likes <!-- bb:ignore rule=mybatis.no-dollar-substitution reason="ORDER BY whitelist" -->,
+	// which suppresses the findings of the given rules in the following element.
+	DirectiveKindIgnore DirectiveKind = "ignore"
+	// DirectiveKindConfig represents the directive likes <!-- bb:config max-in-list=500 -->,
+	// which overrides the parameters of the rules for the following statement.
+	DirectiveKindConfig DirectiveKind = "config"
+)
+
+// Directive represents a bytebase directive written in the xml comment likes <!-- bb:ignore rule=rule reason="reason" -->.
+// The directive is applied to the element which follows it.
+type Directive struct {
+	// Kind is the kind of the directive.
+	Kind DirectiveKind
+	// Args is the map of the directive arguments, key is the name of the argument, value is the value of the argument.
+	Args map[string]string
+	// Line is the line of the xml comment which contains the directive.
+	Line int
+}
+
+// InvalidDirective is the xml comment which starts with DirectivePrefix but cannot be parsed as a directive, likes
+// <!-- bb:todo -->, or the directive which takes no effect, likes the directive placed above the text, the end
+// element or the <cache> element. It does not stop parsing the mapper, and it is not applied to any element.
+type InvalidDirective struct {
+	// Line is the line of the xml comment.
+	Line int
+	// Err is the reason why the comment is not a valid directive.
+	Err error
+}
+
+// ParseDirective parses the bytebase directive from the xml comment content, returns nil if the comment is not a directive.
+func ParseDirective(comment string, line int) (*Directive, error) {
+	trimmed := strings.TrimSpace(comment)
+	if !strings.HasPrefix(trimmed, DirectivePrefix) {
+		return nil, nil
+	}
+	trimmed = strings.TrimPrefix(trimmed, DirectivePrefix)
+	kind, rest := trimmed, ""
+	if idx := strings.IndexFunc(trimmed, unicode.IsSpace); idx >= 0 {
+		kind, rest = trimmed[:idx], trimmed[idx:]
+	}
+	directive := &Directive{
+		Kind: DirectiveKind(kind),
+		Args: make(map[string]string),
+		Line: line,
+	}
+	switch directive.Kind {
+	case DirectiveKindIgnore, DirectiveKindConfig:
+	default:
+		return nil, errors.Errorf("unknown directive %q", directive.Kind)
+	}
+	if err := parseDirectiveArgs(rest, directive.Args); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse arguments of directive %q", directive.Kind)
+	}
+	if directive.Kind == DirectiveKindIgnore && len(directive.Args["rule"]) == 0 {
+		return nil, errors.Errorf("directive %q requires the argument \"rule\"", directive.Kind)
+	}
+	return directive, nil
+}
+
+// parseDirectiveArgs parses the arguments likes `key1=value1 key2="value 2"` into args.
+func parseDirectiveArgs(s string, args map[string]string) error {
+	runes := []rune(s)
+	i := 0
+	for {
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+		if i >= len(runes) {
+			return nil
+		}
+		start := i
+		for i < len(runes) && runes[i] != '=' && !unicode.IsSpace(runes[i]) {
+			i++
+		}
+		key := string(runes[start:i])
+		if i >= len(runes) || runes[i] != '=' {
+			return errors.Errorf("expected '=' after argument %q", key)
+		}
+		if len(key) == 0 {
+			return errors.Errorf("expected argument name before '=' at position %d", i)
+		}
+		// Skip the '='.
+		i++
+		var value string
+		if i < len(runes) && runes[i] == '"' {
+			i++
+			start = i
+			for i < len(runes) && runes[i] != '"' {
+				i++
+			}
+			if i >= len(runes) {
+				return errors.Errorf("expected '\"' to close the value of argument %q", key)
+			}
+			value = string(runes[start:i])
+			// Skip the closing '"'.
+			i++
+		} else {
+			start = i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			value = string(runes[start:i])
+		}
+		args[key] = value
+	}
+}
@@ -44,8 +44,17 @@ func NewIfNode(startElement *xml.StartElement) *IfNode {
 	return node
 }
 
-// RestoreSQL implements Node interface, the if condition will be ignored.
+// RestoreSQL implements Node interface, the if condition will be ignored unless ctx.ConditionEvaluator is set.
 func (n *IfNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
+	if ctx.ConditionEvaluator != nil {
+		ok, err := ctx.ConditionEvaluator.EvaluateTest(n.Test)
+		if err != nil {
+			return errors.Wrapf(err, "failed to evaluate test %q", n.Test)
+		}
+		if !ok {
+			return nil
+		}
+	}
 	if len(n.Children) > 0 {
 		if _, err := w.Write([]byte(" ")); err != nil {
 			return err
@@ -87,8 +96,22 @@ func NewChooseNode(_ *xml.StartElement) *ChooseNode {
 	return &ChooseNode{}
 }
 
-// RestoreSQL implements Node interface.
+// RestoreSQL implements Node interface. If ctx.ConditionEvaluator is set, only the first when node whose test is true,
+// or the otherwise node if there is no such when node, is restored.
 func (n *ChooseNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
+	if ctx.ConditionEvaluator != nil {
+		chosen, err := n.choose(ctx.ConditionEvaluator)
+		if err != nil {
+			return err
+		}
+		if chosen == nil {
+			return nil
+		}
+		if _, err := w.Write([]byte(" ")); err != nil {
+			return err
+		}
+		return chosen.RestoreSQL(ctx, w)
+	}
 	if len(n.Children) > 0 {
 		if _, err := w.Write([]byte(" ")); err != nil {
 			return err
@@ -102,6 +125,26 @@ func (n *ChooseNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
 	return nil
 }
 
+// choose returns the first when node whose test is true, or the otherwise node, or nil if neither exists.
+func (n *ChooseNode) choose(evaluator ConditionEvaluator) (Node, error) {
+	var otherwise Node
+	for _, node := range n.Children {
+		switch child := node.(type) {
+		case *WhenNode:
+			ok, err := evaluator.EvaluateTest(child.Test)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to evaluate test %q", child.Test)
+			}
+			if ok {
+				return child, nil
+			}
+		case *OtherwiseNode:
+			otherwise = child
+		}
+	}
+	return otherwise, nil
+}
+
 func (*ChooseNode) isChildAcceptable(child Node) bool {
 	switch child.(type) {
 	case *WhenNode, *OtherwiseNode:
@@ -431,8 +474,18 @@ func (n *ForEachNode) AddChild(child Node) {
 	n.Children = append(n.Children, child)
 }
 
-// RestoreSQL implements Node interface.
+// RestoreSQL implements Node interface. If ctx.ConditionEvaluator is set and the collection is empty, the foreach
+// node is restored to nothing.
 func (n *ForEachNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
+	if ctx.ConditionEvaluator != nil {
+		empty, err := ctx.ConditionEvaluator.IsCollectionEmpty(n.Collection)
+		if err != nil {
+			return errors.Wrapf(err, "failed to evaluate collection %q", n.Collection)
+		}
+		if empty {
+			return nil
+		}
+	}
 	var partBuilder strings.Builder
 	for _, node := range n.Children {
 		if err := node.RestoreSQL(ctx, &partBuilder); err != nil {
@@ -443,6 +496,10 @@ func (n *ForEachNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
 	if len(part) == 0 {
 		return nil
 	}
+	// The part is written twice, so the size grows by the second part, the open, close, separator and the spaces around them.
+	if err := ctx.grow(len(part) + len(n.Open) + len(n.Close) + len(n.Separator) + 3); err != nil {
+		return err
+	}
 	if _, err := w.Write([]byte(" ")); err != nil {
 		return err
 	}
@@ -587,6 +644,10 @@ func (n *IncludeNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
 	if !ok {
 		return errors.Errorf("refID %s not found", n.RefID)
 	}
+	if err := ctx.enterInclude(refID); err != nil {
+		return err
+	}
+	defer ctx.leaveInclude()
 
 	// Set all the properties.
 	// It is safe we don't check whether the variable exists, because property element can only be child of include element,
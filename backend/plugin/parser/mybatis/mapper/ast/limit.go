@@ -0,0 +1,77 @@
+// Package ast defines the abstract syntax tree of mybatis mapper xml.
+package ast
+
+import (
+	"fmt"
+)
+
+const (
+	// DefaultMaxIncludeDepth is the default max depth of the nested <include> elements.
+	DefaultMaxIncludeDepth = 32
+	// DefaultMaxOutputSize is the default max bytes of the SQL restored from a statement.
+	DefaultMaxOutputSize = 16 * 1024 * 1024
+)
+
+// ExpansionLimitKind is the kind of the expansion limit.
+type ExpansionLimitKind string
+
+const (
+	// ExpansionLimitIncludeDepth is the limit of the depth of the nested <include> elements.
+	ExpansionLimitIncludeDepth ExpansionLimitKind = "include depth"
+	// ExpansionLimitOutputSize is the limit of the bytes of the restored SQL.
+	ExpansionLimitOutputSize ExpansionLimitKind = "output size"
+	// ExpansionLimitFileOutputSize is the limit of the total bytes of the SQL restored from the statements in a mapper
+	// file, it is checked by the callers restoring all the statements in the file.
+	ExpansionLimitFileOutputSize ExpansionLimitKind = "file output size"
+)
+
+// ExpansionLimitError is the error returned if restoring the statement exceeds the expansion limit, for example,
+// the include chain is too deep or circular, or the foreach expansion makes the restored SQL too large.
+type ExpansionLimitError struct {
+	Kind  ExpansionLimitKind
+	Limit int
+	// RefID is the refid of the <include> element which exceeds the include depth limit.
+	RefID string
+}
+
+// Error implements error interface.
+func (e *ExpansionLimitError) Error() string {
+	switch e.Kind {
+	case ExpansionLimitIncludeDepth:
+		return fmt.Sprintf("include %q exceeds the max include depth %d, the include chain may be circular", e.RefID, e.Limit)
+	case ExpansionLimitFileOutputSize:
+		return fmt.Sprintf("restored SQL of the mapper file exceeds the max file output size %d bytes", e.Limit)
+	}
+	return fmt.Sprintf("restored SQL exceeds the max output size %d bytes", e.Limit)
+}
+
+// enterInclude increases the include depth, and returns the ExpansionLimitError if the depth exceeds the limit.
+func (r *RestoreContext) enterInclude(refID string) error {
+	limit := r.MaxIncludeDepth
+	if limit <= 0 {
+		limit = DefaultMaxIncludeDepth
+	}
+	if r.includeDepth >= limit {
+		return &ExpansionLimitError{Kind: ExpansionLimitIncludeDepth, Limit: limit, RefID: refID}
+	}
+	r.includeDepth++
+	return nil
+}
+
+func (r *RestoreContext) leaveInclude() {
+	r.includeDepth--
+}
+
+// grow records the bytes produced by restoring the statement, and returns the ExpansionLimitError if the size
+// exceeds the limit.
+func (r *RestoreContext) grow(n int) error {
+	limit := r.MaxOutputSize
+	if limit <= 0 {
+		limit = DefaultMaxOutputSize
+	}
+	r.outputSize += n
+	if r.outputSize > limit {
+		return &ExpansionLimitError{Kind: ExpansionLimitOutputSize, Limit: limit}
+	}
+	return nil
+}
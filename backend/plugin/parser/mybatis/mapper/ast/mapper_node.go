@@ -18,6 +18,7 @@ type MapperNode struct {
 
 // RestoreSQL implements Node interface.
 func (n *MapperNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
+	ctx.CurrentNamespace = n.Namespace
 	for _, node := range n.Children {
 		if err := node.RestoreSQL(ctx, w); err != nil {
 			return err
@@ -48,7 +49,7 @@ func (n *MapperNode) AddChild(child Node) {
 func (*MapperNode) isChildAcceptable(child Node) bool {
 	// https://github.com/mybatis/mybatis-3/blob/master/src/main/resources/org/apache/ibatis/builder/xml/mybatis-3-mapper.dtd#L19
 	switch child.(type) {
-	case *SQLNode, *QueryNode:
+	case *SQLNode, *QueryNode, *ParameterMapNode, *ResultMapNode:
 		return true
 	default:
 		return false
@@ -32,6 +32,38 @@ type RestoreContext struct {
 	// RestoreDataNodePlaceholder is the placeholder for restoring data node, it may be different in different engine.
 	// For example, in MySQL, it is "?", in PostgreSQL, it is "$1".
 	RestoreDataNodePlaceholder string
+
+	// RestoreTraceComment is true if each restored statement should be prefixed with the comment likes
+	// /* mapper: com.acme.UserMapper.selectByFilter */, so the statement is attributable to its source.
+	RestoreTraceComment bool
+	// CurrentNamespace is the namespace of the mapper being restored, it is used for internal calculation.
+	CurrentNamespace string
+
+	// StatementDelimiter is the delimiter written after each restored statement, the default is
+	// StatementDelimiterSemicolonNewline.
+	StatementDelimiter StatementDelimiter
+
+	// ConditionEvaluator decides the branches of the dynamic SQL to restore, all the branches are restored if it is nil.
+	ConditionEvaluator ConditionEvaluator
+
+	// MaxIncludeDepth is the max depth of the nested <include> elements, 0 means DefaultMaxIncludeDepth.
+	MaxIncludeDepth int
+	// MaxOutputSize is the max bytes of the SQL restored from a statement, 0 means DefaultMaxOutputSize.
+	// Restoring the statement returns the ExpansionLimitError if either limit is exceeded.
+	MaxOutputSize int
+	// includeDepth is the depth of the <include> being restored.
+	includeDepth int
+	// outputSize is the bytes produced by restoring the current statement.
+	outputSize int
+}
+
+// ConditionEvaluator evaluates the conditions of the dynamic SQL, it is used to restore the SQL for a specific
+// combination of the parameters.
+type ConditionEvaluator interface {
+	// EvaluateTest evaluates the test attribute of <if> and <when>.
+	EvaluateTest(test string) (bool, error)
+	// IsCollectionEmpty returns true if the collection of <foreach> is empty, the empty foreach is restored to nothing.
+	IsCollectionEmpty(collection string) (bool, error)
 }
 
 // WithRestoreDataNodePlaceholder set the placeholder for restoring data node.
@@ -45,6 +77,13 @@ func (r *RestoreContext) WithRestoreDataNodePlaceholder(placeholder string) *Res
 	return r
 }
 
+// WithRestoreTraceComment prefixes each restored statement with the statement identity comment, for example:
+// /* mapper: com.acme.UserMapper.selectByFilter */ SELECT * FROM user WHERE name = ?;
+func (r *RestoreContext) WithRestoreTraceComment() *RestoreContext {
+	r.RestoreTraceComment = true
+	return r
+}
+
 var (
 	_ Node = (*RootNode)(nil)
 	_ Node = (*EmptyNode)(nil)
@@ -53,6 +92,10 @@ var (
 // RootNode represents the root node of the AST.
 type RootNode struct {
 	Children []Node
+	// Directives is the map of bytebase directives, key is the node which follows the directives in mybatis mapper xml.
+	Directives map[Node][]*Directive
+	// InvalidDirectives is the xml comments which look like bytebase directives but are malformed, in document order.
+	InvalidDirectives []*InvalidDirective
 }
 
 // MybatisSQLLineMapping represents the line mapping of the SQL statement in Mybatis mapper xml.
@@ -124,3 +167,37 @@ func (*EmptyNode) AddChild(Node) {
 func (*EmptyNode) isChildAcceptable(Node) bool {
 	return false
 }
+
+// GetChildren returns the children of the node, returns nil if the node does not have children.
+// The property children of the include node are not returned, because they are not a part of the SQL statement.
+func GetChildren(node Node) []Node {
+	switch n := node.(type) {
+	case *RootNode:
+		return n.Children
+	case *MapperNode:
+		return n.Children
+	case *QueryNode:
+		return n.Children
+	case *DataNode:
+		return n.Children
+	case *IfNode:
+		return n.Children
+	case *ChooseNode:
+		return n.Children
+	case *WhenNode:
+		return n.Children
+	case *OtherwiseNode:
+		return n.Children
+	case *TrimNode:
+		return n.Children
+	case *WhereNode:
+		return n.trimNode.Children
+	case *SetNode:
+		return n.trimNode.Children
+	case *ForEachNode:
+		return n.Children
+	case *SQLNode:
+		return n.Children
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Package ast defines the abstract syntax tree of mybatis mapper xml.
+package ast
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+var (
+	_ Node = (*ParameterMapNode)(nil)
+	_ Node = (*ParameterMappingNode)(nil)
+)
+
+// ParameterMapNode represents the legacy parameter map in mybatis mapper xml likes
+// <parameterMap id="orderParams" type="Order"><parameter property="id" jdbcType="INTEGER"/></parameterMap>.
+// The statement references it by the parameterMap attribute, and the parameters are bound to the "?" in order.
+type ParameterMapNode struct {
+	ID string
+	// Type is the raw value of the type attribute, it is the class name or the type alias of the parameter object.
+	Type string
+	// Parameters is the parameter mappings in the declaration order.
+	Parameters []*ParameterMappingNode
+}
+
+// NewParameterMapNode creates a new parameter map node.
+func NewParameterMapNode(startElement *xml.StartElement) *ParameterMapNode {
+	n := &ParameterMapNode{}
+	for _, attr := range startElement.Attr {
+		switch attr.Name.Local {
+		case "id":
+			n.ID = attr.Value
+		case "type":
+			n.Type = attr.Value
+		}
+	}
+	return n
+}
+
+// AddChild adds a child to the parameter map node.
+func (n *ParameterMapNode) AddChild(child Node) {
+	if !n.isChildAcceptable(child) {
+		return
+	}
+	n.Parameters = append(n.Parameters, child.(*ParameterMappingNode))
+}
+
+func (*ParameterMapNode) isChildAcceptable(child Node) bool {
+	// https://github.com/mybatis/mybatis-3/blob/master/src/main/resources/org/apache/ibatis/builder/xml/mybatis-3-mapper.dtd
+	_, ok := child.(*ParameterMappingNode)
+	return ok
+}
+
+// RestoreSQL implements Node interface, parameter map node does not restore to SQL.
+func (*ParameterMapNode) RestoreSQL(*RestoreContext, io.Writer) error {
+	return nil
+}
+
+// ParameterMappingNode represents a parameter element in the parameter map likes
+// <parameter property="id" javaType="int" jdbcType="INTEGER" mode="IN"/>.
+type ParameterMappingNode struct {
+	Property     string
+	JavaType     string
+	JdbcType     string
+	Mode         string
+	ResultMap    string
+	TypeHandler  string
+	NumericScale string
+}
+
+// NewParameterMappingNode creates a new parameter mapping node.
+func NewParameterMappingNode(startElement *xml.StartElement) *ParameterMappingNode {
+	n := &ParameterMappingNode{}
+	for _, attr := range startElement.Attr {
+		switch attr.Name.Local {
+		case "property":
+			n.Property = attr.Value
+		case "javaType":
+			n.JavaType = attr.Value
+		case "jdbcType":
+			n.JdbcType = attr.Value
+		case "mode":
+			n.Mode = attr.Value
+		case "resultMap":
+			n.ResultMap = attr.Value
+		case "typeHandler":
+			n.TypeHandler = attr.Value
+		case "numericScale":
+			n.NumericScale = attr.Value
+		}
+	}
+	return n
+}
+
+func (*ParameterMappingNode) isChildAcceptable(Node) bool {
+	return false
+}
+
+// AddChild adds a child to the parameter mapping node.
+func (*ParameterMappingNode) AddChild(Node) {
+}
+
+// RestoreSQL implements Node interface.
+func (*ParameterMappingNode) RestoreSQL(*RestoreContext, io.Writer) error {
+	return nil
+}
@@ -35,10 +35,35 @@ type QueryNode struct {
 	Children []Node
 	// Line is the line of the <select><update><delete><insert> tag.
 	Line int
+	// Timeout is the raw value of the timeout attribute, in seconds.
+	Timeout string
+	// FetchSize is the raw value of the fetchSize attribute.
+	FetchSize string
+	// FlushCache is the raw value of the flushCache attribute.
+	FlushCache string
+	// UseCache is the raw value of the useCache attribute.
+	UseCache string
+	// StatementType is the raw value of the statementType attribute, can be STATEMENT, PREPARED or CALLABLE.
+	StatementType string
+	// Lang is the raw value of the lang attribute.
+	Lang string
+	// LanguageDriver is the language driver of the statement body, the body of non-XML language driver is parsed as
+	// a ScriptNode.
+	LanguageDriver LanguageDriver
+	// ParameterMap is the raw value of the legacy parameterMap attribute, it is the id of the <parameterMap>.
+	ParameterMap string
+	// ParameterType is the raw value of the parameterType attribute, it is the class name or the type alias.
+	ParameterType string
+	// ResultType is the raw value of the resultType attribute, it is the class name or the type alias.
+	ResultType string
+	// ResultMap is the raw value of the resultMap attribute, it is the comma separated ids of the <resultMap>.
+	ResultMap string
 }
 
 // RestoreSQL implements Node interface.
 func (n *QueryNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
+	// The output size limit is for each statement.
+	ctx.outputSize = 0
 	var sb strings.Builder
 	for _, node := range n.Children {
 		if err := node.RestoreSQL(ctx, &sb); err != nil {
@@ -47,25 +72,38 @@ func (n *QueryNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
 	}
 	stmt := sb.String()
 	trimmed := strings.TrimSpace(stmt)
-	if len(trimmed) == 0 {
+	// Skip the statement which is restored to nothing but semicolons, so no delimiter is written for it.
+	if len(strings.TrimRight(trimmed, "; \t\r\n")) == 0 {
 		return nil
 	}
-	if _, err := w.Write([]byte(trimmed)); err != nil {
-		return err
-	}
-	if !strings.HasSuffix(trimmed, ";") {
-		if _, err := w.Write([]byte(";")); err != nil {
+	if ctx.RestoreTraceComment {
+		if _, err := w.Write([]byte(n.traceComment(ctx.CurrentNamespace))); err != nil {
 			return err
 		}
 	}
-	if _, err := w.Write([]byte("\n")); err != nil {
+	if _, err := w.Write([]byte(trimmed)); err != nil {
+		return err
+	}
+	if err := writeStatementDelimiter(ctx, w, trimmed); err != nil {
 		return err
 	}
 	ctx.SQLLastLineToOriginalLineMapping[ctx.CurrentLastLine] = n.Line
-	ctx.CurrentLastLine++
+	ctx.CurrentLastLine += statementDelimiterLines(ctx)
 	return nil
 }
 
+// traceComment returns the comment which identifies the statement, followed by a space. The comment is in the same
+// line of the statement to keep the line mapping.
+func (n *QueryNode) traceComment(namespace string) string {
+	identity := n.ID
+	if namespace != "" {
+		identity = namespace + "." + n.ID
+	}
+	// Avoid closing the comment early.
+	identity = strings.ReplaceAll(identity, "*/", "* /")
+	return "/* mapper: " + identity + " */ "
+}
+
 // AddChild adds a child to the query node.
 func (n *QueryNode) AddChild(child Node) {
 	if !n.isChildAcceptable(child) {
@@ -77,7 +115,7 @@ func (n *QueryNode) AddChild(child Node) {
 func (*QueryNode) isChildAcceptable(child Node) bool {
 	// https://github.com/mybatis/mybatis-3/blob/master/src/main/resources/org/apache/ibatis/builder/xml/mybatis-3-mapper.dtd#L19
 	switch child.(type) {
-	case *DataNode, *IncludeNode, *TrimNode, *WhereNode, *SetNode, *ForEachNode, *ChooseNode, *SQLNode, *IfNode:
+	case *DataNode, *IncludeNode, *TrimNode, *WhereNode, *SetNode, *ForEachNode, *ChooseNode, *SQLNode, *IfNode, *ScriptNode:
 	default:
 		return false
 	}
@@ -99,10 +137,32 @@ func NewQueryNode(startEle *xml.StartElement, line int) *QueryNode {
 	}
 
 	for _, attr := range startEle.Attr {
-		if attr.Name.Local == "id" {
+		switch attr.Name.Local {
+		case "id":
 			n.ID = attr.Value
+		case "timeout":
+			n.Timeout = attr.Value
+		case "fetchSize":
+			n.FetchSize = attr.Value
+		case "flushCache":
+			n.FlushCache = attr.Value
+		case "useCache":
+			n.UseCache = attr.Value
+		case "statementType":
+			n.StatementType = attr.Value
+		case "lang":
+			n.Lang = attr.Value
+		case "parameterMap":
+			n.ParameterMap = attr.Value
+		case "parameterType":
+			n.ParameterType = attr.Value
+		case "resultType":
+			n.ResultType = attr.Value
+		case "resultMap":
+			n.ResultMap = attr.Value
 		}
 	}
+	n.LanguageDriver = GetLanguageDriver(n.Lang)
 	n.Line = line
 	return n
 }
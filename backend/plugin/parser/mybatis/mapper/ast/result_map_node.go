@@ -0,0 +1,49 @@
+// Package ast defines the abstract syntax tree of mybatis mapper xml.
+package ast
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+var (
+	_ Node = (*ResultMapNode)(nil)
+)
+
+// ResultMapNode represents a result map in mybatis mapper xml likes <resultMap id="blogResult" type="Blog">...</resultMap>.
+// Only the attributes are kept, the mappings of the columns are ignored.
+type ResultMapNode struct {
+	ID string
+	// Type is the raw value of the type attribute, it is the class name or the type alias.
+	Type string
+	// Extends is the id of the result map extended by this result map.
+	Extends string
+}
+
+// NewResultMapNode creates a new result map node.
+func NewResultMapNode(startElement *xml.StartElement) *ResultMapNode {
+	n := &ResultMapNode{}
+	for _, attr := range startElement.Attr {
+		switch attr.Name.Local {
+		case "id":
+			n.ID = attr.Value
+		case "type":
+			n.Type = attr.Value
+		case "extends":
+			n.Extends = attr.Value
+		}
+	}
+	return n
+}
+
+func (*ResultMapNode) isChildAcceptable(Node) bool {
+	return false
+}
+
+// AddChild implements Node interface, the children of result map node are ignored.
+func (*ResultMapNode) AddChild(Node) {}
+
+// RestoreSQL implements Node interface, result map node does not restore to SQL.
+func (*ResultMapNode) RestoreSQL(*RestoreContext, io.Writer) error {
+	return nil
+}
@@ -0,0 +1,148 @@
+// Package ast defines the abstract syntax tree of mybatis mapper xml.
+package ast
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	_ Node = (*ScriptNode)(nil)
+)
+
+// LanguageDriver is the scripting language driver of the statement body.
+type LanguageDriver string
+
+const (
+	// LanguageDriverXML is the default XML language driver, the statement body is the dynamic SQL xml.
+	LanguageDriverXML LanguageDriver = "xml"
+	// LanguageDriverFreeMarker is the FreeMarker language driver provided by mybatis-freemarker.
+	LanguageDriverFreeMarker LanguageDriver = "freemarker"
+	// LanguageDriverVelocity is the Velocity language driver provided by mybatis-velocity.
+	LanguageDriverVelocity LanguageDriver = "velocity"
+	// LanguageDriverUnknown is the language driver we cannot recognize, the statement body is kept as the raw text.
+	LanguageDriverUnknown LanguageDriver = "unknown"
+)
+
+// GetLanguageDriver returns the language driver of the lang attribute, which can be the alias or the class name of the driver.
+func GetLanguageDriver(lang string) LanguageDriver {
+	switch strings.TrimSpace(lang) {
+	case "", "org.apache.ibatis.scripting.xmltags.XMLLanguageDriver", "org.apache.ibatis.scripting.defaults.RawLanguageDriver":
+		return LanguageDriverXML
+	case "org.mybatis.scripting.freemarker.FreeMarkerLanguageDriver":
+		return LanguageDriverFreeMarker
+	case "org.mybatis.scripting.velocity.VelocityLanguageDriver", "org.mybatis.scripting.velocity.Driver":
+		return LanguageDriverVelocity
+	}
+	switch strings.ToLower(strings.TrimSpace(lang)) {
+	case "xml", "raw":
+		return LanguageDriverXML
+	case "freemarker":
+		return LanguageDriverFreeMarker
+	case "velocity":
+		return LanguageDriverVelocity
+	}
+	return LanguageDriverUnknown
+}
+
+// ScriptNode represents the body of the statement written in the non-XML language driver, likes
+// <select lang="velocity">...</select>. The body is not parsed as the dynamic SQL xml.
+type ScriptNode struct {
+	// Driver is the language driver of the script.
+	Driver LanguageDriver
+	// Text is the raw text of the script.
+	Text string
+}
+
+// NewScriptNode creates a new script node.
+func NewScriptNode(driver LanguageDriver, text string) *ScriptNode {
+	return &ScriptNode{
+		Driver: driver,
+		Text:   text,
+	}
+}
+
+var (
+	freeMarkerCommentRegexp       = regexp.MustCompile(`(?s)<#--.*?-->`)
+	freeMarkerParameterRegexp     = regexp.MustCompile(`<@p\s[^>]*/>`)
+	freeMarkerDirectiveRegexp     = regexp.MustCompile(`</?[#@][^>]*>`)
+	freeMarkerInterpolationRegexp = regexp.MustCompile(`\$\{[^}]*\}`)
+
+	velocityCommentRegexp   = regexp.MustCompile(`(?s)#\*.*?\*#|##[^\n]*`)
+	velocityParameterRegexp = regexp.MustCompile(`@\{[^}]*\}`)
+	velocityReferenceRegexp = regexp.MustCompile(`\$!?(\{[^}]*\}|[A-Za-z_][\w.]*)`)
+	velocityDirectiveRegexp = regexp.MustCompile(`#\{?(if|elseif|else|end|foreach|set|repeat|in|where|mset|trim|macro)\b\}?`)
+	// velocityWherePrefixRegexp matches the leading AND / OR which is removed by the #where() directive.
+	velocityWherePrefixRegexp = regexp.MustCompile(`(?i)\bWHERE(\s+)(AND|OR)\b`)
+)
+
+// RestoreSQL implements Node interface. It is the best effort to restore the SQL from the script, the directives
+// are removed and the parameters and references are restored to ctx.RestoreDataNodePlaceholder. The script of the
+// unknown language driver is restored as it is.
+func (n *ScriptNode) RestoreSQL(ctx *RestoreContext, w io.Writer) error {
+	text := n.Text
+	switch n.Driver {
+	case LanguageDriverFreeMarker:
+		text = freeMarkerCommentRegexp.ReplaceAllString(text, "")
+		text = freeMarkerParameterRegexp.ReplaceAllLiteralString(text, ctx.RestoreDataNodePlaceholder)
+		text = freeMarkerDirectiveRegexp.ReplaceAllString(text, "")
+		text = freeMarkerInterpolationRegexp.ReplaceAllLiteralString(text, ctx.RestoreDataNodePlaceholder)
+	case LanguageDriverVelocity:
+		text = velocityCommentRegexp.ReplaceAllString(text, "")
+		text = removeVelocityDirectives(text)
+		text = velocityWherePrefixRegexp.ReplaceAllString(text, "WHERE$1")
+		text = velocityParameterRegexp.ReplaceAllLiteralString(text, ctx.RestoreDataNodePlaceholder)
+		text = velocityReferenceRegexp.ReplaceAllLiteralString(text, ctx.RestoreDataNodePlaceholder)
+	}
+	textNode := &TextNode{Text: text}
+	return textNode.RestoreSQL(ctx, w)
+}
+
+// removeVelocityDirectives removes the velocity directives and their arguments, the #where() and #mset() directives
+// provided by mybatis-velocity are restored to WHERE and SET.
+func removeVelocityDirectives(text string) string {
+	var sb strings.Builder
+	for {
+		loc := velocityDirectiveRegexp.FindStringSubmatchIndex(text)
+		if loc == nil {
+			_, _ = sb.WriteString(text)
+			return sb.String()
+		}
+		_, _ = sb.WriteString(text[:loc[0]])
+		switch text[loc[2]:loc[3]] {
+		case "where":
+			_, _ = sb.WriteString(" WHERE ")
+		case "mset":
+			_, _ = sb.WriteString(" SET ")
+		}
+		text = text[loc[1]:]
+		// Skip the arguments in the balanced parentheses.
+		trimmed := strings.TrimLeft(text, " \t")
+		if !strings.HasPrefix(trimmed, "(") {
+			continue
+		}
+		depth := 0
+		for i, c := range trimmed {
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				depth--
+			}
+			if depth == 0 {
+				text = trimmed[i+1:]
+				break
+			}
+		}
+		if depth != 0 {
+			text = ""
+		}
+	}
+}
+
+func (*ScriptNode) isChildAcceptable(Node) bool {
+	return false
+}
+
+// AddChild implements Node interface, script node does not have child.
+func (*ScriptNode) AddChild(Node) {}
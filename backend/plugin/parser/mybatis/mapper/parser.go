@@ -3,7 +3,9 @@ package mapper
 
 import (
 	"encoding/xml"
+	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -45,18 +47,29 @@ func (p *Parser) NewRestoreContext() *ast.RestoreContext {
 
 // Parse parses the mybatis mapper xml statements, building AST without recursion, returns the root node of the AST.
 func (p *Parser) Parse() (*ast.RootNode, error) {
-	root := &ast.RootNode{}
+	root := &ast.RootNode{
+		Directives: make(map[ast.Node][]*ast.Directive),
+	}
 	// To avoid recursion, we use stack to store the start element and node, and consume the token one by one.
 	// The length of start element stack is always equal to the length of node stack - 1, because the root nod
 	// is not in the start element stack.
 	var startElementStack []*xml.StartElement
 	nodeStack := []ast.Node{root}
+	// pendingDirectives is the directives which are waiting for the following start element.
+	var pendingDirectives []*ast.Directive
+	// script is not nil if we are reading the body of the statement written in the non-XML language driver.
+	var script *scriptBody
 
 	for {
 		token, err := p.d.Token()
 		if err != nil {
 			if err == io.EOF {
 				if len(startElementStack) == 0 {
+					dropDirectives(root, pendingDirectives)
+					// The dropped directives are recorded when the following token is read, so sort them by the line.
+					sort.SliceStable(root.InvalidDirectives, func(i, j int) bool {
+						return root.InvalidDirectives[i].Line < root.InvalidDirectives[j].Line
+					})
 					return root, nil
 				}
 				return nil, errors.Errorf("expected to read the end element of %q, but got EOF", startElementStack[len(startElementStack)-1].Name.Local)
@@ -65,6 +78,10 @@ func (p *Parser) Parse() (*ast.RootNode, error) {
 		}
 		switch ele := token.(type) {
 		case xml.StartElement:
+			if script != nil {
+				script.writeStartElement(&ele)
+				continue
+			}
 			newNode := p.newNodeByStartElement(&ele)
 			if ele.Name.Local == "sql" {
 				node, ok := newNode.(*ast.SQLNode)
@@ -73,16 +90,36 @@ func (p *Parser) Parse() (*ast.RootNode, error) {
 				}
 				p.sqlMap[newNode.(*ast.SQLNode).ID] = node
 			}
+			if len(pendingDirectives) > 0 {
+				attachDirectives(root, newNode, ele.Name.Local, pendingDirectives)
+				pendingDirectives = nil
+			}
+			if queryNode, ok := newNode.(*ast.QueryNode); ok && queryNode.LanguageDriver != ast.LanguageDriverXML {
+				script = &scriptBody{queryNode: queryNode}
+			}
 			startElementStack = append(startElementStack, &ele)
 			nodeStack = append(nodeStack, newNode)
 
 		case xml.EndElement:
+			if script != nil {
+				if script.depth > 0 {
+					script.writeEndElement(&ele)
+					continue
+				}
+				if text := strings.TrimSpace(script.sb.String()); len(text) > 0 {
+					script.queryNode.AddChild(ast.NewScriptNode(script.queryNode.LanguageDriver, text))
+				}
+				script = nil
+			}
 			if len(startElementStack) == 0 {
 				return nil, errors.Errorf("unexpected end element %q", ele.Name.Local)
 			}
 			if ele.Name.Local != startElementStack[len(startElementStack)-1].Name.Local {
 				return nil, errors.Errorf("expected to read the name of end element is %q, but got %q", startElementStack[len(startElementStack)-1].Name.Local, ele.Name.Local)
 			}
+			// The directives can only be applied to the following start element in the same parent.
+			dropDirectives(root, pendingDirectives)
+			pendingDirectives = nil
 			// We will pop the start element stack and node stack at the same time.
 			startElementStack = startElementStack[:len(startElementStack)-1]
 			popNode := nodeStack[len(nodeStack)-1]
@@ -97,10 +134,16 @@ func (p *Parser) Parse() (*ast.RootNode, error) {
 					p.currentLine++
 				}
 			}
+			if script != nil {
+				_, _ = script.sb.Write(ele)
+				continue
+			}
 			trimmed := strings.TrimSpace(string(ele))
 			if len(trimmed) == 0 {
 				continue
 			}
+			dropDirectives(root, pendingDirectives)
+			pendingDirectives = nil
 			dataNode := ast.NewDataNode([]byte(trimmed))
 			if err := dataNode.Scan(); err != nil {
 				return nil, errors.Wrapf(err, "cannot parse data node")
@@ -110,6 +153,15 @@ func (p *Parser) Parse() (*ast.RootNode, error) {
 			}
 			nodeStack[len(nodeStack)-1].AddChild(dataNode)
 		case xml.Comment:
+			if script == nil {
+				directive, err := ast.ParseDirective(string(ele), p.currentLine)
+				if err != nil {
+					// The malformed directive is only a comment for mybatis, so it should not fail the whole mapper.
+					root.InvalidDirectives = append(root.InvalidDirectives, &ast.InvalidDirective{Line: p.currentLine, Err: err})
+				} else if directive != nil {
+					pendingDirectives = append(pendingDirectives, directive)
+				}
+			}
 			for _, b := range ele {
 				if b == '\n' {
 					p.currentLine++
@@ -131,6 +183,68 @@ func (p *Parser) Parse() (*ast.RootNode, error) {
 	}
 }
 
+// attachDirectives applies the directives to the node of the following start element. The bb:config directive is only
+// read for the statement, and the bb:ignore directive is only read for the elements which can contain the findings,
+// the directives placed above the other elements are recorded as the invalid directives, so the user knows that they
+// take no effect.
+func attachDirectives(root *ast.RootNode, node ast.Node, name string, directives []*ast.Directive) {
+	for _, directive := range directives {
+		switch directive.Kind {
+		case ast.DirectiveKindConfig:
+			if _, ok := node.(*ast.QueryNode); !ok {
+				root.InvalidDirectives = append(root.InvalidDirectives, &ast.InvalidDirective{
+					Line: directive.Line,
+					Err:  errors.Errorf("directive %q is not followed by a statement, but <%s>", directive.Kind, name),
+				})
+				continue
+			}
+		case ast.DirectiveKindIgnore:
+			switch node.(type) {
+			case *ast.EmptyNode, *ast.ResultMapNode, *ast.ParameterMapNode, *ast.ParameterMappingNode, *ast.PropertyNode:
+				root.InvalidDirectives = append(root.InvalidDirectives, &ast.InvalidDirective{
+					Line: directive.Line,
+					Err:  errors.Errorf("directive %q is not followed by a statement or an element in the statement, but <%s>", directive.Kind, name),
+				})
+				continue
+			}
+		}
+		root.Directives[node] = append(root.Directives[node], directive)
+	}
+}
+
+// dropDirectives records the directives which are not followed by an element directly as the invalid directives.
+func dropDirectives(root *ast.RootNode, directives []*ast.Directive) {
+	for _, directive := range directives {
+		root.InvalidDirectives = append(root.InvalidDirectives, &ast.InvalidDirective{
+			Line: directive.Line,
+			Err:  errors.Errorf("directive %q is not followed by an element directly", directive.Kind),
+		})
+	}
+}
+
+// scriptBody is the body of the statement written in the non-XML language driver, the body is read as the raw text
+// because it is not the dynamic SQL xml.
+type scriptBody struct {
+	queryNode *ast.QueryNode
+	sb        strings.Builder
+	// depth is the depth of the start elements in the body.
+	depth int
+}
+
+func (s *scriptBody) writeStartElement(startElement *xml.StartElement) {
+	s.depth++
+	_, _ = s.sb.WriteString("<" + startElement.Name.Local)
+	for _, attr := range startElement.Attr {
+		_, _ = s.sb.WriteString(fmt.Sprintf(" %s=%q", attr.Name.Local, attr.Value))
+	}
+	_, _ = s.sb.WriteString(">")
+}
+
+func (s *scriptBody) writeEndElement(endElement *xml.EndElement) {
+	s.depth--
+	_, _ = s.sb.WriteString("</" + endElement.Name.Local + ">")
+}
+
 // newNodeByStartElement returns the node related to the startElement, for example, returns QueryNode for
 // start element which name is "select", "update", "insert", "delete". If the startElement is unacceptable,
 // returns an emptyNode instead.
@@ -162,6 +276,12 @@ func (p *Parser) newNodeByStartElement(startElement *xml.StartElement) ast.Node
 		return ast.NewIncludeNode(startElement)
 	case "property":
 		return ast.NewPropertyNode(startElement)
+	case "parameterMap":
+		return ast.NewParameterMapNode(startElement)
+	case "resultMap":
+		return ast.NewResultMapNode(startElement)
+	case "parameter":
+		return ast.NewParameterMappingNode(startElement)
 	}
 	return ast.NewEmptyNode()
 }
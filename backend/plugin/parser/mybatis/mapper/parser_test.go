@@ -142,3 +142,372 @@ func TestRestoreWithLineMapping(t *testing.T) {
 		require.Equal(t, tc.lineMapping, lineMapping)
 	}
 }
+
+func TestParseDirective(t *testing.T) {
+	testCases := []struct {
+		xml        string
+		directives []*ast.Directive
+		// invalidDirectives is the errors of the invalid directives.
+		invalidDirectives []string
+	}{
+		{
+			xml: `<mapper namespace="com.bytebase.test">
+	<!-- normal comment -->
+	<!-- bb:ignore rule=mybatis.no-dollar-substitution reason="ORDER BY whitelist" -->
+	<select id="select">SELECT * FROM t ORDER BY ${orderBy}</select>
+</mapper>`,
+			directives: []*ast.Directive{
+				{
+					Kind: ast.DirectiveKindIgnore,
+					Args: map[string]string{
+						"rule":   "mybatis.no-dollar-substitution",
+						"reason": "ORDER BY whitelist",
+					},
+					Line: 3,
+				},
+			},
+		},
+		{
+			xml: `<mapper namespace="com.bytebase.test">
+	<!-- bb:ignore reason="missing rule" -->
+	<select id="select">SELECT 1</select>
+</mapper>`,
+			invalidDirectives: []string{`directive "ignore" requires the argument "rule"`},
+		},
+		{
+			xml: `<mapper namespace="com.bytebase.test">
+	<!-- bb:ignore rule=mybatis.no-dollar-substitution reason="unclosed -->
+	<select id="select">SELECT 1</select>
+</mapper>`,
+			invalidDirectives: []string{`failed to parse arguments of directive "ignore": expected '"' to close the value of argument "reason"`},
+		},
+		{
+			// The malformed directives are kept as invalid directives, the valid one is still applied.
+			xml: `<mapper namespace="com.bytebase.test">
+	<!-- bb:todo -->
+	<!-- bb: note -->
+	<!-- bb:config max-in-list=500 -->
+	<select id="select">SELECT 1</select>
+</mapper>`,
+			directives: []*ast.Directive{
+				{
+					Kind: ast.DirectiveKindConfig,
+					Args: map[string]string{"max-in-list": "500"},
+					Line: 4,
+				},
+			},
+			invalidDirectives: []string{`unknown directive "todo"`, `unknown directive ""`},
+		},
+		{
+			// The directives which take no effect are reported.
+			xml: `<mapper namespace="com.bytebase.test">
+	<select id="select">SELECT 1</select>
+	<!-- bb:ignore rule=mybatis.no-dollar-substitution -->
+	<cache/>
+	<!-- bb:config max-in-list=500 -->
+	<sql id="columns">id<if test="true">, name</if></sql>
+	<!-- bb:ignore rule=mybatis.no-dollar-substitution -->
+</mapper>
+<!-- bb:ignore rule=mybatis.no-dollar-substitution -->`,
+			invalidDirectives: []string{
+				`directive "ignore" is not followed by a statement or an element in the statement, but <cache>`,
+				`directive "config" is not followed by a statement, but <sql>`,
+				`directive "ignore" is not followed by an element directly`,
+				`directive "ignore" is not followed by an element directly`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		parser := NewParser(tc.xml)
+		node, err := parser.Parse()
+		require.NoError(t, err)
+		mapperNode, ok := node.Children[0].(*ast.MapperNode)
+		require.True(t, ok)
+		require.Equal(t, tc.directives, node.Directives[mapperNode.Children[0]])
+		var invalidDirectives []string
+		for _, invalidDirective := range node.InvalidDirectives {
+			invalidDirectives = append(invalidDirectives, invalidDirective.Err.Error())
+		}
+		require.Equal(t, tc.invalidDirectives, invalidDirectives)
+	}
+}
+
+func TestParseLanguageDriver(t *testing.T) {
+	xml := `<mapper namespace="com.bytebase.test">
+	<select id="selectByFreeMarker" lang="freemarker"><![CDATA[
+		SELECT * FROM t
+		<#-- Filter by name. -->
+		<#if name??>WHERE name = <@p name="name"/></#if>
+	]]></select>
+	<select id="selectByVelocity" lang="org.mybatis.scripting.velocity.VelocityLanguageDriver">
+		SELECT * FROM t
+		#where()
+			#if($_parameter.name) AND name = @{name} #end
+		#end
+	</select>
+	<select id="selectByCustom" lang="com.acme.CustomDriver">
+		SELECT * FROM t WHERE <filter column="name"/> {{ name }}
+	</select>
+	<select id="selectByXML" lang="XML">SELECT * FROM t WHERE id = #{id}</select>
+</mapper>`
+	parser := NewParser(xml)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+	mapperNode, ok := node.Children[0].(*ast.MapperNode)
+	require.True(t, ok)
+	require.Len(t, mapperNode.Children, 4)
+	var drivers []ast.LanguageDriver
+	for _, child := range mapperNode.Children {
+		queryNode, ok := child.(*ast.QueryNode)
+		require.True(t, ok)
+		drivers = append(drivers, queryNode.LanguageDriver)
+	}
+	require.Equal(t, []ast.LanguageDriver{ast.LanguageDriverFreeMarker, ast.LanguageDriverVelocity, ast.LanguageDriverUnknown, ast.LanguageDriverXML}, drivers)
+
+	var sb strings.Builder
+	err = node.RestoreSQL(parser.NewRestoreContext().WithRestoreDataNodePlaceholder("?"), &sb)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT * FROM t
+		
+		WHERE name = ?;
+SELECT * FROM t
+		 WHERE 
+			  name = ?;
+SELECT * FROM t WHERE <filter column="name"></filter> {{ name }};
+SELECT * FROM t WHERE id = ?;
+`, sb.String())
+}
+
+func TestRestoreWithTraceComment(t *testing.T) {
+	xml := `<mapper namespace="com.acme.UserMapper">
+	<select id="selectByFilter">
+		SELECT * FROM user
+		WHERE name = #{name}
+	</select>
+	<update id="updateName">UPDATE user SET name = #{name} WHERE id = #{id}</update>
+	<delete id="deleteNothing"><if test="false">DELETE FROM user</if></delete>
+</mapper>`
+	parser := NewParser(xml)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+	var sb strings.Builder
+	lineMapping, err := node.RestoreSQLWithLineMapping(parser.NewRestoreContext().WithRestoreDataNodePlaceholder("?").WithRestoreTraceComment(), &sb)
+	require.NoError(t, err)
+	require.Equal(t, `/* mapper: com.acme.UserMapper.selectByFilter */ SELECT * FROM user
+		WHERE name = ?;
+/* mapper: com.acme.UserMapper.updateName */ UPDATE user SET name = ? WHERE id = ?;
+/* mapper: com.acme.UserMapper.deleteNothing */ DELETE FROM user;
+`, sb.String())
+	require.Equal(t, []*ast.MybatisSQLLineMapping{
+		{SQLLastLine: 2, OriginalEleLine: 2},
+		{SQLLastLine: 3, OriginalEleLine: 6},
+		{SQLLastLine: 4, OriginalEleLine: 7},
+	}, lineMapping)
+}
+
+func TestRestoreWithStatementDelimiter(t *testing.T) {
+	xml := `<mapper namespace="com.acme.UserMapper">
+	<select id="selectByID">SELECT * FROM user WHERE id = #{id}</select>
+	<update id="updateName">UPDATE user SET name = #{name};</update>
+	<delete id="deleteAll">
+		DELETE FROM user -- clean up
+	</delete>
+	<select id="empty"> ; </select>
+	<select id="selectName">SELECT '--' AS name</select>
+</mapper>`
+	testCases := []struct {
+		delimiter   ast.StatementDelimiter
+		want        string
+		lineMapping []*ast.MybatisSQLLineMapping
+	}{
+		{
+			delimiter: "",
+			want: `SELECT * FROM user WHERE id = ?;
+UPDATE user SET name = ?;
+DELETE FROM user -- clean up
+;
+SELECT '--' AS name;
+`,
+			lineMapping: []*ast.MybatisSQLLineMapping{
+				{SQLLastLine: 1, OriginalEleLine: 2},
+				{SQLLastLine: 2, OriginalEleLine: 3},
+				{SQLLastLine: 4, OriginalEleLine: 4},
+				{SQLLastLine: 5, OriginalEleLine: 8},
+			},
+		},
+		{
+			delimiter: ast.StatementDelimiterSemicolon,
+			want: `SELECT * FROM user WHERE id = ?;UPDATE user SET name = ?;DELETE FROM user -- clean up
+;SELECT '--' AS name;`,
+		},
+		{
+			delimiter: ast.StatementDelimiterGo,
+			want: `SELECT * FROM user WHERE id = ?
+GO
+UPDATE user SET name = ?;
+GO
+DELETE FROM user -- clean up
+GO
+SELECT '--' AS name
+GO
+`,
+			lineMapping: []*ast.MybatisSQLLineMapping{
+				{SQLLastLine: 1, OriginalEleLine: 2},
+				{SQLLastLine: 3, OriginalEleLine: 3},
+				{SQLLastLine: 5, OriginalEleLine: 4},
+				{SQLLastLine: 7, OriginalEleLine: 8},
+			},
+		},
+		{
+			delimiter: ast.StatementDelimiterNone,
+			want: `SELECT * FROM user WHERE id = ?
+UPDATE user SET name = ?;
+DELETE FROM user -- clean up
+SELECT '--' AS name
+`,
+		},
+	}
+	for _, tc := range testCases {
+		parser := NewParser(xml)
+		node, err := parser.Parse()
+		require.NoError(t, err)
+		var sb strings.Builder
+		lineMapping, err := node.RestoreSQLWithLineMapping(parser.NewRestoreContext().WithRestoreDataNodePlaceholder("?").WithStatementDelimiter(tc.delimiter), &sb)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, sb.String(), tc.delimiter)
+		if tc.lineMapping != nil {
+			require.Equal(t, tc.lineMapping, lineMapping, tc.delimiter)
+		}
+	}
+}
+
+func TestRestoreExpansionLimit(t *testing.T) {
+	xml := `<mapper namespace="com.acme.UserMapper">
+	<sql id="a">SELECT * FROM user WHERE <include refid="b"/></sql>
+	<sql id="b">id = #{id} AND <include refid="a"/></sql>
+	<select id="selectCircular"><include refid="a"/></select>
+	<sql id="c1">id</sql>
+	<sql id="c2"><include refid="c1"/></sql>
+	<sql id="c3"><include refid="c2"/></sql>
+	<select id="selectNested">SELECT <include refid="c3"/> FROM user</select>
+	<select id="selectByIDs">
+		SELECT * FROM user WHERE id IN <foreach collection="ids" item="id" open="(" separator="," close=")">#{id}</foreach>
+	</select>
+	<select id="selectByOrder">SELECT * FROM user ORDER BY ${orderBy}</select>
+</mapper>`
+	parser := NewParser(xml)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+	restore := func(id string, ctx *ast.RestoreContext) (string, error) {
+		for _, child := range node.Children[0].(*ast.MapperNode).Children {
+			if queryNode, ok := child.(*ast.QueryNode); ok && queryNode.ID == id {
+				var sb strings.Builder
+				err := queryNode.RestoreSQL(ctx.WithRestoreDataNodePlaceholder("?"), &sb)
+				return sb.String(), err
+			}
+		}
+		return "", nil
+	}
+
+	// The circular include is stopped by the default include depth limit.
+	_, err = restore("selectCircular", parser.NewRestoreContext())
+	var limitErr *ast.ExpansionLimitError
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, &ast.ExpansionLimitError{Kind: ast.ExpansionLimitIncludeDepth, Limit: ast.DefaultMaxIncludeDepth, RefID: "a"}, limitErr)
+
+	ctx := parser.NewRestoreContext()
+	ctx.MaxIncludeDepth = 3
+	sql, err := restore("selectNested", ctx)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT id FROM user;\n", sql)
+	ctx.MaxIncludeDepth = 2
+	_, err = restore("selectNested", ctx)
+	require.EqualError(t, err, `include "c1" exceeds the max include depth 2, the include chain may be circular`)
+
+	// The size limit counts the part duplicated by the foreach.
+	ctx = parser.NewRestoreContext()
+	ctx.MaxOutputSize = 64
+	sql, err = restore("selectByIDs", ctx)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM user WHERE id IN (? , ?);\n", sql)
+	ctx.MaxOutputSize = 36
+	_, err = restore("selectByIDs", ctx)
+	require.EqualError(t, err, "restored SQL exceeds the max output size 36 bytes")
+
+	// The size limit counts the bytes written for the variable only once.
+	ctx = parser.NewRestoreContext()
+	ctx.Variable["orderBy"] = "name"
+	ctx.MaxOutputSize = len("SELECT * FROM user ORDER BY name")
+	sql, err = restore("selectByOrder", ctx)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM user ORDER BY name;\n", sql)
+	ctx.MaxOutputSize--
+	_, err = restore("selectByOrder", ctx)
+	require.ErrorAs(t, err, &limitErr)
+}
+
+func TestRestoreWhatIf(t *testing.T) {
+	xml := `<mapper namespace="com.acme.UserMapper">
+	<sql id="columns">id, name</sql>
+	<select id="selectByFilter">
+		SELECT <include refid="columns"/> FROM user
+		<where>
+			<if test="name != null and name != ''">AND name = #{name}</if>
+			<if test="ids != null &amp;&amp; ids.size() &gt; 0">
+				AND id IN <foreach collection="ids" item="id" open="(" separator="," close=")">#{id}</foreach>
+			</if>
+			<choose>
+				<when test="status == null">AND status = 'ACTIVE'</when>
+				<when test="status.isEmpty()">AND status IS NULL</when>
+				<otherwise>AND status = #{status}</otherwise>
+			</choose>
+		</where>
+	</select>
+</mapper>`
+	node, err := NewParser(xml).Parse()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		toggles map[string]bool
+		sql     string
+	}{
+		{
+			toggles: map[string]bool{},
+			sql:     "SELECT id, name FROM user WHERE status = 'ACTIVE';",
+		},
+		{
+			toggles: map[string]bool{"name": true, "status": true},
+			sql:     "SELECT id, name FROM user WHERE name = ? AND status = ?;",
+		},
+		{
+			toggles: map[string]bool{"ids": true, "status": false},
+			sql:     "SELECT id, name FROM user WHERE id IN (? , ?) AND status = 'ACTIVE';",
+		},
+	}
+	for _, tc := range testCases {
+		sql, err := RestoreWhatIf(node, "com.acme.UserMapper.selectByFilter", tc.toggles)
+		require.NoError(t, err)
+		require.Equal(t, tc.sql, sql, tc.toggles)
+	}
+
+	_, err = RestoreWhatIf(node, "selectByName", nil)
+	require.EqualError(t, err, `statement "selectByName" not found`)
+
+	// The tests out of the supported subset are unknown, the branches are taken instead of failing the statement.
+	node, err = NewParser(`<mapper namespace="com.acme.UserMapper">
+	<select id="selectByType">
+		SELECT * FROM user
+		<where>
+			<if test="type == 'A'.toString()">AND type = #{type}</if>
+			<if test="id % 2 == 0">AND id = #{id}</if>
+			<if test="@com.acme.Strings@isNotBlank(name)">AND name = #{name}</if>
+			<if test="tags.contains('vip', 'new')">AND vip = 1</if>
+			<if test="status != null">AND status = #{status}</if>
+		</where>
+	</select>
+</mapper>`).Parse()
+	require.NoError(t, err)
+	sql, err := RestoreWhatIf(node, "selectByType", map[string]bool{"status": false})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM user WHERE type = ? AND id = ? AND name = ? AND vip = 1;", sql)
+}
@@ -0,0 +1,464 @@
+package mapper
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+
+	"github.com/bytebase/bytebase/backend/plugin/parser/mybatis/mapper/ast"
+)
+
+// RestoreWhatIf restores the SQL skeleton of the statement for a combination of the parameter presence, for example,
+// {"name": true, "ids": false} means the name is provided and the ids is null or empty. The statement id can be
+// either "namespace.id" or "id". The parameters are restored to "?", the whitespaces are collapsed, and the missing
+// toggles are treated as not provided.
+//
+// The tests of <if> and <when> are evaluated by the presence of the parameters on a best-effort basis. The tests
+// below are true if the name or the list is provided:
+//
+//	name != null
+//	name != ''
+//	list.size() > 0
+//	!list.isEmpty()
+//	name
+//
+// The tests below are true if the name or the list is not provided:
+//
+//	name == null
+//	name == ''
+//	list.size() == 0
+//	list.isEmpty()
+//
+// The other tests are evaluated as:
+//   - comparing the parameter with the other values, likes "type == 'A'", is true if the parameter is provided.
+//   - the test which cannot be parsed or evaluated, likes "type == 'A'.toString()" or "id % 2 == 0", is unknown,
+//     and the branch is taken, so one unsupported test does not fail the whole statement.
+//
+// The <foreach> is restored if its collection is provided.
+func RestoreWhatIf(root *ast.RootNode, statementID string, toggles map[string]bool) (string, error) {
+	for _, child := range root.Children {
+		mapperNode, ok := child.(*ast.MapperNode)
+		if !ok {
+			continue
+		}
+		sqlMap := make(map[string]*ast.SQLNode)
+		var queryNode *ast.QueryNode
+		for _, node := range mapperNode.Children {
+			switch n := node.(type) {
+			case *ast.SQLNode:
+				sqlMap[n.ID] = n
+			case *ast.QueryNode:
+				if queryNode == nil && (n.ID == statementID || mapperNode.Namespace+"."+n.ID == statementID) {
+					queryNode = n
+				}
+			}
+		}
+		if queryNode == nil {
+			continue
+		}
+		ctx := &ast.RestoreContext{
+			SQLMap:                           sqlMap,
+			Variable:                         make(map[string]string),
+			SQLLastLineToOriginalLineMapping: make(map[int]int),
+			CurrentLastLine:                  1,
+			RestoreDataNodePlaceholder:       "?",
+			CurrentNamespace:                 mapperNode.Namespace,
+			ConditionEvaluator:               &presenceEvaluator{toggles: toggles},
+		}
+		var sb strings.Builder
+		if err := queryNode.RestoreSQL(ctx, &sb); err != nil {
+			return "", errors.Wrapf(err, "failed to restore statement %q", statementID)
+		}
+		// The whitespaces are collapsed because the skeleton is for reading.
+		return strings.Join(strings.Fields(sb.String()), " "), nil
+	}
+	return "", errors.Errorf("statement %q not found", statementID)
+}
+
+// presenceEvaluator evaluates the OGNL expression by the presence of the parameters.
+type presenceEvaluator struct {
+	toggles map[string]bool
+}
+
+// EvaluateTest implements ast.ConditionEvaluator, the test which cannot be evaluated is true.
+func (e *presenceEvaluator) EvaluateTest(test string) (bool, error) {
+	v, err := e.evaluate(test)
+	if err != nil {
+		// The test is unknown because it is out of the supported subset of OGNL, take the branch as the best effort.
+		return true, nil
+	}
+	return v, nil
+}
+
+func (e *presenceEvaluator) evaluate(test string) (bool, error) {
+	tokens, err := tokenizeOGNL(test)
+	if err != nil {
+		return false, err
+	}
+	p := &ognlParser{tokens: tokens, evaluator: e}
+	v, err := p.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, errors.Errorf("unexpected token %q", p.tokens[p.pos].text)
+	}
+	return v.truthy(), nil
+}
+
+// IsCollectionEmpty implements ast.ConditionEvaluator.
+func (e *presenceEvaluator) IsCollectionEmpty(collection string) (bool, error) {
+	return !e.isPresent(strings.TrimSpace(collection)), nil
+}
+
+// isPresent returns the toggle of the parameter, the toggle of "user" applies to "user.name" if the latter is not set.
+func (e *presenceEvaluator) isPresent(name string) bool {
+	for {
+		if present, ok := e.toggles[name]; ok {
+			return present
+		}
+		idx := strings.LastIndex(name, ".")
+		if idx < 0 {
+			return false
+		}
+		name = name[:idx]
+	}
+}
+
+type ognlTokenType int
+
+const (
+	ognlTokenIdentifier ognlTokenType = iota
+	ognlTokenString
+	ognlTokenNumber
+	ognlTokenOperator
+)
+
+type ognlToken struct {
+	tp   ognlTokenType
+	text string
+}
+
+// ognlOperators is the operators sorted by the length descending, so the longer one is matched first.
+var ognlOperators = []string{"==", "!=", ">=", "<=", "&&", "||", ">", "<", "!", "(", ")", ","}
+
+// ognlWordOperators is the operators in word form, they are normalized to the symbol form.
+var ognlWordOperators = map[string]string{
+	"and": "&&", "or": "||", "not": "!", "eq": "==", "neq": "!=", "gt": ">", "gte": ">=", "lt": "<", "lte": "<=",
+}
+
+func tokenizeOGNL(expression string) ([]ognlToken, error) {
+	runes := []rune(expression)
+	var tokens []ognlToken
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != r {
+				i++
+			}
+			if i >= len(runes) {
+				return nil, errors.Errorf("unclosed string in %q", expression)
+			}
+			tokens = append(tokens, ognlToken{tp: ognlTokenString, text: string(runes[start+1 : i])})
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, ognlToken{tp: ognlTokenNumber, text: string(runes[start:i])})
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$' || runes[i] == '.') {
+				i++
+			}
+			word := string(runes[start:i])
+			if operator, ok := ognlWordOperators[word]; ok {
+				tokens = append(tokens, ognlToken{tp: ognlTokenOperator, text: operator})
+				continue
+			}
+			tokens = append(tokens, ognlToken{tp: ognlTokenIdentifier, text: word})
+		default:
+			matched := false
+			for _, operator := range ognlOperators {
+				if strings.HasPrefix(string(runes[i:]), operator) {
+					tokens = append(tokens, ognlToken{tp: ognlTokenOperator, text: operator})
+					i += len([]rune(operator))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, errors.Errorf("unsupported character %q in %q", r, expression)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+type ognlValueKind int
+
+const (
+	ognlValueBool ognlValueKind = iota
+	ognlValueNull
+	ognlValueString
+	ognlValueNumber
+	// ognlValueParameter is the parameter, or its property.
+	ognlValueParameter
+	// ognlValueSize is the size or length of the parameter.
+	ognlValueSize
+)
+
+type ognlValue struct {
+	kind ognlValueKind
+	// present is true if the parameter is provided, for ognlValueParameter and ognlValueSize.
+	present bool
+	b       bool
+	s       string
+	n       float64
+}
+
+func (v ognlValue) truthy() bool {
+	switch v.kind {
+	case ognlValueBool:
+		return v.b
+	case ognlValueNull:
+		return false
+	case ognlValueParameter, ognlValueSize:
+		return v.present
+	case ognlValueString:
+		return v.s != ""
+	case ognlValueNumber:
+		return v.n != 0
+	}
+	return false
+}
+
+// ognlParser is the recursive descent parser evaluating the OGNL expression while parsing.
+type ognlParser struct {
+	tokens    []ognlToken
+	pos       int
+	evaluator *presenceEvaluator
+}
+
+func (p *ognlParser) peekOperator(operators ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].tp != ognlTokenOperator {
+		return "", false
+	}
+	for _, operator := range operators {
+		if p.tokens[p.pos].text == operator {
+			return operator, true
+		}
+	}
+	return "", false
+}
+
+func (p *ognlParser) parseOr() (ognlValue, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return ognlValue{}, err
+	}
+	for {
+		if _, ok := p.peekOperator("||"); !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return ognlValue{}, err
+		}
+		left = ognlValue{kind: ognlValueBool, b: left.truthy() || right.truthy()}
+	}
+}
+
+func (p *ognlParser) parseAnd() (ognlValue, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return ognlValue{}, err
+	}
+	for {
+		if _, ok := p.peekOperator("&&"); !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return ognlValue{}, err
+		}
+		left = ognlValue{kind: ognlValueBool, b: left.truthy() && right.truthy()}
+	}
+}
+
+func (p *ognlParser) parseNot() (ognlValue, error) {
+	if _, ok := p.peekOperator("!"); ok {
+		p.pos++
+		v, err := p.parseNot()
+		if err != nil {
+			return ognlValue{}, err
+		}
+		return ognlValue{kind: ognlValueBool, b: !v.truthy()}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *ognlParser) parseComparison() (ognlValue, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return ognlValue{}, err
+	}
+	operator, ok := p.peekOperator("==", "!=", ">", ">=", "<", "<=")
+	if !ok {
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parseOperand()
+	if err != nil {
+		return ognlValue{}, err
+	}
+	return ognlValue{kind: ognlValueBool, b: compareOGNL(left, operator, right)}, nil
+}
+
+func (p *ognlParser) parseOperand() (ognlValue, error) {
+	if p.pos >= len(p.tokens) {
+		return ognlValue{}, errors.New("unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token.tp {
+	case ognlTokenString:
+		return ognlValue{kind: ognlValueString, s: token.text}, nil
+	case ognlTokenNumber:
+		n, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return ognlValue{}, errors.Errorf("invalid number %q", token.text)
+		}
+		return ognlValue{kind: ognlValueNumber, n: n}, nil
+	case ognlTokenOperator:
+		if token.text != "(" {
+			return ognlValue{}, errors.Errorf("unexpected token %q", token.text)
+		}
+		v, err := p.parseOr()
+		if err != nil {
+			return ognlValue{}, err
+		}
+		if _, ok := p.peekOperator(")"); !ok {
+			return ognlValue{}, errors.New("expected \")\"")
+		}
+		p.pos++
+		return v, nil
+	}
+
+	switch token.text {
+	case "null":
+		return ognlValue{kind: ognlValueNull}, nil
+	case "true", "false":
+		return ognlValue{kind: ognlValueBool, b: token.text == "true"}, nil
+	}
+	name := token.text
+	method := ""
+	// The method call likes "list.size()", the arguments are not supported.
+	if _, ok := p.peekOperator("("); ok {
+		idx := strings.LastIndex(name, ".")
+		if idx < 0 {
+			return ognlValue{}, errors.Errorf("unsupported function %q", name)
+		}
+		name, method = name[:idx], name[idx+1:]
+		p.pos++
+		if _, ok := p.peekOperator(")"); !ok {
+			return ognlValue{}, errors.Errorf("unsupported arguments of method %q", method)
+		}
+		p.pos++
+	} else if strings.HasSuffix(name, ".length") {
+		name, method = strings.TrimSuffix(name, ".length"), "length"
+	}
+	present := p.evaluator.isPresent(name)
+	switch method {
+	case "":
+		return ognlValue{kind: ognlValueParameter, present: present}, nil
+	case "size", "length":
+		return ognlValue{kind: ognlValueSize, present: present}, nil
+	case "isEmpty":
+		return ognlValue{kind: ognlValueBool, b: !present}, nil
+	default:
+		// The other methods likes "name.trim()" keep the presence of the parameter.
+		return ognlValue{kind: ognlValueParameter, present: present}, nil
+	}
+}
+
+// compareOGNL compares the values by the presence semantics, see RestoreWhatIf for details.
+func compareOGNL(left ognlValue, operator string, right ognlValue) bool {
+	if right.kind == ognlValueParameter || right.kind == ognlValueSize {
+		left, right = right, left
+		switch operator {
+		case ">":
+			operator = "<"
+		case "<":
+			operator = ">"
+		case ">=":
+			operator = "<="
+		case "<=":
+			operator = ">="
+		}
+	}
+	switch left.kind {
+	case ognlValueParameter:
+		// Comparing with null or empty string checks the presence.
+		if right.kind == ognlValueNull || (right.kind == ognlValueString && right.s == "") {
+			if operator == "==" {
+				return !left.present
+			}
+			return left.present
+		}
+		return left.present
+	case ognlValueSize:
+		if right.kind != ognlValueNumber {
+			return left.present
+		}
+		if !left.present {
+			return compareNumber(0, operator, right.n)
+		}
+		// The provided collection is not empty, the comparison is true if any positive size satisfies it.
+		switch operator {
+		case ">", ">=", "!=":
+			return true
+		case "==", "<=":
+			return right.n >= 1
+		case "<":
+			return right.n > 1
+		}
+		return false
+	}
+	if left.kind == ognlValueNumber && right.kind == ognlValueNumber {
+		return compareNumber(left.n, operator, right.n)
+	}
+	equal := left == right
+	if operator == "!=" {
+		return !equal
+	}
+	return equal
+}
+
+func compareNumber(left float64, operator string, right float64) bool {
+	switch operator {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	case ">":
+		return left > right
+	case ">=":
+		return left >= right
+	case "<":
+		return left < right
+	case "<=":
+		return left <= right
+	}
+	return false
+}